@@ -22,12 +22,21 @@ import (
 
 // CertificateSentinelSpec defines the desired state of CertificateSentinel
 type CertificateSentinelSpec struct {
-	// Targets is the definition of K8s Objects to watch on the cluster and with what ServiceAccount
-	Target Target `json:"target"`
+	// Target is the definition of K8s Objects to watch on the cluster and with what ServiceAccount
+	Target Target `json:"target,omitempty"`
+
+	// Targets is an optional slice of additional Target definitions, their discoveries are merged into one status and one report
+	Targets []Target `json:"targets,omitempty"`
 
 	// Alerts is where the alerts will be sent to
 	Alert Alert `json:"alert"`
 
+	// Rules is an optional slice of user defined policy checks evaluated against every discovered certificate, their violations are reported alongside expiry
+	Rules []PolicyRule `json:"rules,omitempty"`
+
+	// RevocationCheck optionally checks discovered leaf certificates against their OCSP responders and CRL distribution points - defaults to not sending any revocation requests
+	RevocationCheck *RevocationCheck `json:"revocationCheck,omitempty"`
+
 	// ScanningInterval is how frequently the controller scans the cluster for these targets - defaults to 60s
 	ScanningInterval int `json:"scanningInterval,omitempty"`
 
@@ -39,20 +48,50 @@ type CertificateSentinelSpec struct {
 type Target struct {
 	// TargetName is a simple DNS/k8s compliant name for identification purposes
 	TargetName string `json:"name"`
-	// Namespaces is the slice of namespaces to watch on the cluster - can be a single wildcard to watch all namespaces
+	// Namespaces is the slice of namespaces to watch on the cluster - can be a single wildcard to watch all namespaces, globs such as team-*, regular expressions wrapped in slashes such as /^team-(a|b)$/, or any of these prefixed with ! to exclude the matches - a list of only ! exclusions watches every other namespace
 	Namespaces []string `json:"namespaces"`
+	// ExcludeNamespaces is an optional slice of namespace names or patterns to remove from the matched namespaces
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// ExcludeSystemNamespaces removes the kube-* and openshift-* system namespaces from the matched namespaces - defaults to false
+	ExcludeSystemNamespaces bool `json:"excludeSystemNamespaces,omitempty"`
 	// NamespaceLabels is an optional slice of key pair labels to target, which will limit the scope of the matched namespaces to only ones with those labels
 	NamespaceLabels []LabelSelector `json:"namespaceLabels,omitempty"`
-	// Kind can be either ConfigMap or Secret
+	// Kind can be either ConfigMap or Secret, or one of the cluster-scoped kinds that carry a caBundle: ValidatingWebhookConfiguration, MutatingWebhookConfiguration, APIService, or CustomResourceDefinition - Namespaces are ignored for the cluster-scoped kinds.  Service and TLSEndpoint dial live TLS endpoints with the TLSProbe options.  Any other Kind is scanned with the DataSelectors
 	Kind string `json:"kind"`
 	// APIVersion corresponds to the target kind apiVersion, so v1 is all really
 	APIVersion string `json:"apiVersion"`
+	// DataSelectors is an optional slice of JSONPath expressions that locate the data to scan when the Kind is not a Secret or ConfigMap
+	DataSelectors []DataSelector `json:"dataSelectors,omitempty"`
+	// TLSProbe provides the options used to dial live TLS endpoints when the Kind is Service or TLSEndpoint
+	TLSProbe TLSProbe `json:"tlsProbe,omitempty"`
 	// TargetLabels is an optional slice of key pair labels to target, which will limit the scope of the matched objects to only ones with those labels
 	TargetLabels []LabelSelector `json:"targetLabels,omitempty"`
 	// ServiceAccount is the ServiceAccount to use in order to scan the cluster - this allows for separate RBAC per targeted object
 	ServiceAccount string `json:"serviceAccount"`
 	// DaysOut is the slice of days out alerts should be triggered at.  Defaults to 30, 60, and 90
 	DaysOut []int `json:"daysOut,omitempty"`
+	// Severities maps daysOut thresholds to severity levels, ie `{daysOut: 7, severity: critical}`.  Defaults to critical at 7, warning at 30, and info at 90 days out
+	Severities []SeverityThreshold `json:"severities,omitempty"`
+	// IncludeDetails adds the SANs, serial number, fingerprint, key and signature algorithms, key usages, and full subject and issuer of each certificate to the status.  Defaults to false to keep the status small
+	IncludeDetails bool `json:"includeDetails,omitempty"`
+	// Policy enables the weak key, weak signature, missing SAN, validity period, and restricted wildcard checks on each certificate.  Defaults to no policy checks
+	Policy *CertificatePolicy `json:"policy,omitempty"`
+	// ChainVerification assembles the certificates in each data key into a chain and reports broken chains, missing intermediates, out of order bundles, and issuers that expire before the leaf.  Defaults to not verifying chains
+	ChainVerification *ChainVerification `json:"chainVerification,omitempty"`
+}
+
+// TLSProbe provides the options used to dial TLS endpoints and capture the certificate chain being served
+type TLSProbe struct {
+	// Endpoints is the slice of host:port addresses to dial when the Kind is TLSEndpoint
+	Endpoints []string `json:"endpoints,omitempty"`
+	// Ports is an optional slice of Service ports to dial when the Kind is Service - defaults to ports named https or tls, and ports 443 and 8443
+	Ports []int `json:"ports,omitempty"`
+	// ServerName overrides the SNI server name sent in the TLS handshake - defaults to the dialed hostname
+	ServerName string `json:"serverName,omitempty"`
+	// StartTLS negotiates the upgrade to TLS in-protocol before the handshake, can be smtp, ldap, or postgres
+	StartTLS string `json:"startTLS,omitempty"`
+	// Timeout is the number of seconds to wait on each endpoint - defaults to 5
+	Timeout int `json:"timeout,omitempty"`
 }
 
 // CertificateSentinelStatus defines the observed state of CertificateSentinel
@@ -63,10 +102,20 @@ type CertificateSentinelStatus struct {
 	ExpiringCertificates int `json:"expiringCertificates,omitempty"`
 	// LastReportSent is last time the report was sent out
 	LastReportSent int64 `json:"lastReportSent,omitempty"`
+	// LastReportAttempt is the last time delivery of the report was attempted
+	LastReportAttempt int64 `json:"lastReportAttempt,omitempty"`
+	// LastReportError is the error from the last failed report delivery, cleared once a report is delivered
+	LastReportError string `json:"lastReportError,omitempty"`
+	// ReportDeliveries tracks each route of a report that has not been delivered to every recipient yet, cleared once it has
+	ReportDeliveries []ReportDelivery `json:"reportDeliveries,omitempty"`
+	// FailedTargets lists the targets that could not be scanned, their certificates from the last successful scan are kept
+	FailedTargets []string `json:"failedTargets,omitempty"`
 }
 
 // CertificateInformation provides the status structure of what certificates have been discovered on the cluster
 type CertificateInformation struct {
+	// TargetName provides the name of the Target the certificate was discovered by
+	TargetName string `json:"targetName,omitempty"`
 	// Namespace provides what namespace the certificate object was found in
 	Namespace string `json:"namespace"`
 	// Name provides the name of the certificate object
@@ -77,8 +126,12 @@ type CertificateInformation struct {
 	APIVersion string `json:"apiVersion"`
 	// DataKey is the key for the data structure found
 	DataKey string `json:"dataKey"`
-	// Expiration is the expiration date in YYYY-MM-DD
+	// Expiration is the expiration date as a string, kept for compatibility - use NotAfter instead
 	Expiration string `json:"expiration"`
+	// NotBefore is the date the certificate becomes valid
+	NotBefore metav1.Time `json:"notBefore,omitempty"`
+	// NotAfter is the date the certificate expires
+	NotAfter metav1.Time `json:"notAfter,omitempty"`
 	// Name provides the name of the certificate object
 	CommonName string `json:"commonName"`
 	// CertificateAuthorityCommonName provides the Common Name of the signing Certificate Authority
@@ -87,6 +140,66 @@ type CertificateInformation struct {
 	IsCertificateAuthority bool `json:"isCertificateAuthority"`
 	// TriggeredDaysOut provides the slice of days out that triggered the watch
 	TriggeredDaysOut []int `json:"triggeredDaysOut,omitempty"`
+	// Expired is true once the certificate is past its expiration date
+	Expired bool `json:"expired,omitempty"`
+	// NotYetValid is true while the certificate is before its NotBefore date
+	NotYetValid bool `json:"notYetValid,omitempty"`
+	// DaysRemaining is the number of whole days until the certificate expires, negative once it has expired
+	DaysRemaining int `json:"daysRemaining"`
+	// Severity is the severity tier of the certificate - `revoked`, `expired`, or the severity mapped from the target severities it expires within
+	Severity string `json:"severity,omitempty"`
+	// PolicyViolations provides the failed policy checks when the target sets a Policy
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
+	// Revoked is true once the OCSP responder or CRL of the certificate reports it as revoked
+	Revoked bool `json:"revoked,omitempty"`
+	// Revocation provides the result of the last revocation check when the sentinel sets a RevocationCheck
+	Revocation *RevocationStatus `json:"revocation,omitempty"`
+	// Owner provides the routing contact set with the owner annotation on the certificate object
+	Owner string `json:"owner,omitempty"`
+	// Details provides the extended metadata of the certificate when the target sets IncludeDetails
+	Details *CertificateDetails `json:"details,omitempty"`
+	// Kubeconfig provides where the certificate was embedded when it was found in a kubeconfig file
+	Kubeconfig *KubeconfigReference `json:"kubeconfig,omitempty"`
+}
+
+// RevocationCheck enables OCSP and CRL revocation checking of discovered leaf certificates
+type RevocationCheck struct {
+	// Enabled turns on revocation checking, which sends requests to the OCSP responders and CRL distribution points named in the certificates
+	Enabled bool `json:"enabled"`
+	// HTTPProxy is the URL of the proxy revocation requests are sent through.  Defaults to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables of the operator
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// Timeout is the number of seconds to wait on each revocation request.  Defaults to 10
+	Timeout int `json:"timeout,omitempty"`
+	// ScanTimeout is the number of seconds allowed for all of the revocation checks in a scan, the certificates left are reported with an unknown status until the next scan.  Defaults to 60
+	ScanTimeout int `json:"scanTimeout,omitempty"`
+}
+
+// RevocationStatus provides the revocation status of a certificate and where it came from
+type RevocationStatus struct {
+	// Status is the revocation status - `good`, `revoked`, or `unknown` when no responder could be reached
+	Status string `json:"status"`
+	// Source is where the status came from - `ocsp` or `crl`
+	Source string `json:"source,omitempty"`
+	// Responder is the OCSP responder or CRL distribution point URL that answered
+	Responder string `json:"responder,omitempty"`
+	// RevokedAt is when the certificate was revoked
+	RevokedAt *metav1.Time `json:"revokedAt,omitempty"`
+	// Reason is the revocation reason, ie keyCompromise or superseded
+	Reason string `json:"reason,omitempty"`
+	// NextUpdate is when the cached OCSP response or CRL is refreshed
+	NextUpdate *metav1.Time `json:"nextUpdate,omitempty"`
+	// Error explains why the status is unknown
+	Error string `json:"error,omitempty"`
+}
+
+// KubeconfigReference provides the context, cluster, and user names that a kubeconfig embedded certificate belongs to
+type KubeconfigReference struct {
+	// Context is the name of the context that references the cluster or user, empty when none do
+	Context string `json:"context,omitempty"`
+	// Cluster is the name of the cluster, set when the certificate is a cluster CA
+	Cluster string `json:"cluster,omitempty"`
+	// User is the name of the user, set when the certificate is a user client certificate
+	User string `json:"user,omitempty"`
 }
 
 //+kubebuilder:object:root=true
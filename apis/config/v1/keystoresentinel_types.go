@@ -31,6 +31,9 @@ type KeystoreSentinelSpec struct {
 	// Alert is where the alerts will be sent to
 	Alert Alert `json:"alert"`
 
+	// Rules is an optional slice of user defined policy checks evaluated against every discovered certificate, their violations are reported alongside expiry
+	Rules []PolicyRule `json:"rules,omitempty"`
+
 	// ScanningInterval is how frequently the controller scans the cluster for these targets - defaults to 30s
 	ScanningInterval int `json:"scanningInterval,omitempty"`
 
@@ -42,20 +45,32 @@ type KeystoreSentinelSpec struct {
 type KeystoreTarget struct {
 	// TargetName is a simple DNS/k8s compliant name for identification purposes
 	TargetName string `json:"name"`
-	// Namespaces is the slice of namespaces to watch on the cluster - can be a single wildcard to watch all namespaces
+	// Namespaces is the slice of namespaces to watch on the cluster - can be a single wildcard to watch all namespaces, globs such as team-*, regular expressions wrapped in slashes such as /^team-(a|b)$/, or any of these prefixed with ! to exclude the matches - a list of only ! exclusions watches every other namespace
 	Namespaces []string `json:"namespaces"`
+	// ExcludeNamespaces is an optional slice of namespace names or patterns to remove from the matched namespaces
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// ExcludeSystemNamespaces removes the kube-* and openshift-* system namespaces from the matched namespaces - defaults to false
+	ExcludeSystemNamespaces bool `json:"excludeSystemNamespaces,omitempty"`
 	// NamespaceLabels is an optional slice of key pair labels to target, which will limit the scope of the matched namespaces to only ones with those labels
 	NamespaceLabels []LabelSelector `json:"namespaceLabels,omitempty"`
-	// Kind can be either ConfigMap or Secret
+	// Kind can be either ConfigMap or Secret - any other Kind is scanned with the DataSelectors
 	Kind string `json:"kind"`
 	// APIVersion corresponds to the target kind apiVersion, so v1 is all really
 	APIVersion string `json:"apiVersion"`
+	// DataSelectors is an optional slice of JSONPath expressions that locate the data to scan when the Kind is not a Secret or ConfigMap
+	DataSelectors []DataSelector `json:"dataSelectors,omitempty"`
 	// TargetLabels is an optional slice of key pair labels to target, which will limit the scope of the matched objects to only ones with those labels
 	TargetLabels []LabelSelector `json:"targetLabels,omitempty"`
 	// ServiceAccount is the ServiceAccount to use in order to scan the cluster - this allows for separate RBAC per targeted object
 	ServiceAccount string `json:"serviceAccount"`
 	// DaysOut is the slice of days out alerts should be triggered at.  Defaults to 30, 60, and 90
 	DaysOut []int `json:"daysOut,omitempty"`
+	// Severities maps daysOut thresholds to severity levels, ie `{daysOut: 7, severity: critical}`.  Defaults to critical at 7, warning at 30, and info at 90 days out
+	Severities []SeverityThreshold `json:"severities,omitempty"`
+	// IncludeDetails adds the SANs, serial number, fingerprint, key and signature algorithms, key usages, and full subject and issuer of each certificate to the status.  Defaults to false to keep the status small
+	IncludeDetails bool `json:"includeDetails,omitempty"`
+	// Policy enables the weak key, weak signature, missing SAN, validity period, and restricted wildcard checks on each certificate.  Defaults to no policy checks
+	Policy *CertificatePolicy `json:"policy,omitempty"`
 	// KeystorePassword corresponds to the source for the the KeystorePassword
 	KeystorePassword KeystorePassword `json:"keystorePassword"`
 }
@@ -94,6 +109,12 @@ type KeystoreSentinelStatus struct {
 	KeystoresAtRisk int `json:"keystoresAtRisk,omitempty"`
 	// LastReportSent is the time the report has been sent out by this Operator controller and when
 	LastReportSent int64 `json:"lastReportSent,omitempty"`
+	// LastReportAttempt is the last time delivery of the report was attempted
+	LastReportAttempt int64 `json:"lastReportAttempt,omitempty"`
+	// LastReportError is the error from the last failed report delivery, cleared once a report is delivered
+	LastReportError string `json:"lastReportError,omitempty"`
+	// ReportDeliveries tracks each route of a report that has not been delivered to every recipient yet, cleared once it has
+	ReportDeliveries []ReportDelivery `json:"reportDeliveries,omitempty"`
 }
 
 // KeystoreInformation provides the status structure of what keystores have certificates that have been discovered on the cluster
@@ -110,8 +131,12 @@ type KeystoreInformation struct {
 	DataKey string `json:"dataKey"`
 	// KeystoreAlias is the key for the data structure found
 	KeystoreAlias string `json:"keystoreAlias"`
-	// Expiration is the expiration date in YYYY-MM-DD
+	// Expiration is the expiration date as a string, kept for compatibility - use NotAfter instead
 	Expiration string `json:"expiration"`
+	// NotBefore is the date the certificate becomes valid
+	NotBefore metav1.Time `json:"notBefore,omitempty"`
+	// NotAfter is the date the certificate expires
+	NotAfter metav1.Time `json:"notAfter,omitempty"`
 	// Name provides the name of the certificate object
 	CommonName string `json:"commonName"`
 	// CertificateAuthorityCommonName provides the Common Name of the signing Certificate Authority
@@ -120,6 +145,20 @@ type KeystoreInformation struct {
 	IsCertificateAuthority bool `json:"isCertificateAuthority"`
 	// TriggeredDaysOut provides the slice of days out that triggered the watch
 	TriggeredDaysOut []int `json:"triggeredDaysOut,omitempty"`
+	// Expired is true once the certificate is past its expiration date
+	Expired bool `json:"expired,omitempty"`
+	// NotYetValid is true while the certificate is before its NotBefore date
+	NotYetValid bool `json:"notYetValid,omitempty"`
+	// DaysRemaining is the number of whole days until the certificate expires, negative once it has expired
+	DaysRemaining int `json:"daysRemaining"`
+	// Severity is the severity tier of the certificate - `expired`, or the severity mapped from the target severities it expires within
+	Severity string `json:"severity,omitempty"`
+	// PolicyViolations provides the failed policy checks when the target sets a Policy
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
+	// Owner provides the routing contact set with the owner annotation on the Keystore object
+	Owner string `json:"owner,omitempty"`
+	// Details provides the extended metadata of the certificate when the target sets IncludeDetails
+	Details *CertificateDetails `json:"details,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -30,6 +30,84 @@ type TimeSlice struct {
 	DaysOut int
 }
 
+// SeverityThreshold maps a daysOut threshold to the severity of certificates expiring within it
+type SeverityThreshold struct {
+	// DaysOut is the number of days out the certificate expires within
+	DaysOut int `json:"daysOut"`
+	// Severity is the severity level, can be `info`, `warning`, or `critical`
+	Severity string `json:"severity"`
+}
+
+// CertificateDetails provides the extended metadata of a certificate, only added to the status when a target sets IncludeDetails
+type CertificateDetails struct {
+	// Subject is the full Distinguished Name of the certificate subject
+	Subject string `json:"subject"`
+	// Issuer is the full Distinguished Name of the certificate issuer
+	Issuer string `json:"issuer"`
+	// SerialNumber is the colon separated hex serial number of the certificate
+	SerialNumber string `json:"serialNumber"`
+	// FingerprintSHA256 is the colon separated hex SHA-256 fingerprint of the DER encoded certificate
+	FingerprintSHA256 string `json:"fingerprintSHA256"`
+	// DNSNames are the DNS Subject Alternative Names
+	DNSNames []string `json:"dnsNames,omitempty"`
+	// IPAddresses are the IP Address Subject Alternative Names
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+	// URIs are the URI Subject Alternative Names
+	URIs []string `json:"uris,omitempty"`
+	// EmailAddresses are the email Subject Alternative Names
+	EmailAddresses []string `json:"emailAddresses,omitempty"`
+	// PublicKeyAlgorithm is the public key algorithm, ie `RSA`, `ECDSA`, or `Ed25519`
+	PublicKeyAlgorithm string `json:"publicKeyAlgorithm"`
+	// PublicKeySize is the size of the public key in bits
+	PublicKeySize int `json:"publicKeySize,omitempty"`
+	// SignatureAlgorithm is the algorithm the certificate was signed with, ie `SHA256-RSA`
+	SignatureAlgorithm string `json:"signatureAlgorithm"`
+	// KeyUsage is the list of key usages, ie `DigitalSignature` and `KeyEncipherment`
+	KeyUsage []string `json:"keyUsage,omitempty"`
+	// ExtendedKeyUsage is the list of extended key usages, ie `ServerAuth` and `ClientAuth`
+	ExtendedKeyUsage []string `json:"extendedKeyUsage,omitempty"`
+}
+
+// CertificatePolicy provides the cryptographic hygiene checks run against each discovered certificate
+type CertificatePolicy struct {
+	// MinimumRSAKeySize flags RSA keys with fewer bits.  Defaults to 2048
+	MinimumRSAKeySize int `json:"minimumRSAKeySize,omitempty"`
+	// MaximumValidityDays flags leaf certificates valid for longer, ie 398.  Defaults to not checking the validity period
+	MaximumValidityDays int `json:"maximumValidityDays,omitempty"`
+	// RestrictedWildcardNamespaces flags wildcard certificates found in matching namespaces - literal names, globs such as `prod-*`, or regular expressions wrapped in slashes
+	RestrictedWildcardNamespaces []string `json:"restrictedWildcardNamespaces,omitempty"`
+}
+
+// PolicyViolation provides a single failed policy check of a certificate
+type PolicyViolation struct {
+	// Rule is the name of the failed check, ie `weak-key`, `weak-signature`, `long-validity`, `missing-sans`, or `restricted-wildcard`
+	Rule string `json:"rule"`
+	// Severity is the severity of the violation, can be `info`, `warning`, or `critical`
+	Severity string `json:"severity"`
+	// Message describes the violation
+	Message string `json:"message"`
+}
+
+// PolicyRule provides a user defined policy check, a Common Expression Language (CEL) expression that flags a certificate when it is true
+type PolicyRule struct {
+	// Name is a simple DNS/k8s compliant name for identification purposes, reported as the rule of its violations
+	Name string `json:"name"`
+	// Expression is evaluated against the `cert` and `object` variables, ie `cert.keySize < 3072 && object.namespace.startsWith('prod')`
+	Expression string `json:"expression"`
+	// Severity is the severity of the violation, can be `info`, `warning`, or `critical`.  Defaults to warning
+	Severity string `json:"severity,omitempty"`
+	// Message describes the violation, defaults to naming the rule
+	Message string `json:"message,omitempty"`
+}
+
+// ChainVerification provides the trust anchors certificate chains are verified against
+type ChainVerification struct {
+	// TrustBundle is an optional ConfigMap in the same Namespace as the sentinel holding the PEM encoded trusted roots.  Defaults to the system roots
+	TrustBundle string `json:"trustBundle,omitempty"`
+	// TrustBundleKey is the key in the TrustBundle ConfigMap holding the roots.  Defaults to ca-bundle.crt
+	TrustBundleKey string `json:"trustBundleKey,omitempty"`
+}
+
 // LabelSelector is a struct to target specific assets with matching labels
 type LabelSelector struct {
 	Key    string   `json:"key"`
@@ -37,6 +115,16 @@ type LabelSelector struct {
 	Values []string `json:"value"`
 }
 
+// DataSelector locates certificate or keystore data inside of an object that is not a Secret or ConfigMap
+type DataSelector struct {
+	// Name is an optional friendly name reported as the DataKey - defaults to the JSONPath expression
+	Name string `json:"name,omitempty"`
+	// JSONPath is a kubectl-style JSONPath expression that locates the data in the object, ie `{.spec.tls.caCertificate}`
+	JSONPath string `json:"jsonPath"`
+	// Base64Decode will decode the located value from base64 before it is parsed - defaults to false
+	Base64Decode bool `json:"base64Decode,omitempty"`
+}
+
 // Alert provides the structure of the type of Alert
 type Alert struct {
 	// AlertType - valid values are: 'email' and 'logger'
@@ -45,12 +133,24 @@ type Alert struct {
 	AlertName string `json:"name"`
 	// AlertConfiguration is optional when only using `logger` as the AlertType, but with SMTP it must be defined
 	AlertConfiguration AlertConfiguration `json:"config,omitempty"`
+	// ReportTemplates is an optional ConfigMap in the same Namespace with overrides for the `subject`, `text`, `text_header`, `text_row`, `html`, `html_header`, and `html_row` report templates
+	ReportTemplates string `json:"reportTemplates,omitempty"`
 }
 
 // AlertConfiguration provides the structure of the AlertConfigurations for different Alert Endpoints
 type AlertConfiguration struct {
 	// ReportInterval is the frequency in which Reports would be sent out - can be `daily`, `weekly`, `monthly`, or `debug` which is every 5 minutes.  Defaults to daily.
 	ReportInterval string `json:"reportInterval,omitempty"`
+	// MinimumSeverity only reports certificates at or above the severity, can be `info`, `warning`, `critical`, `expired`, or `revoked`.  Defaults to reporting every certificate that triggered a daysOut
+	MinimumSeverity string `json:"minimumSeverity,omitempty"`
+	// ReportSchedule is an optional cron expression, ie `0 9 * * 1-5`, or descriptor such as `@weekly` that replaces the ReportInterval
+	ReportSchedule string `json:"reportSchedule,omitempty"`
+	// ReportTimeZone is the IANA time zone, ie `America/New_York`, the ReportSchedule and QuietHours are in.  Defaults to UTC
+	ReportTimeZone string `json:"reportTimeZone,omitempty"`
+	// QuietHours is an optional `HH:MM-HH:MM` window in the ReportTimeZone that reports are held until the end of, ie `22:00-07:00`
+	QuietHours string `json:"quietHours,omitempty"`
+	// ClusterName is the cluster name shown in reports, defaults to the API server hostname without an `api.` prefix
+	ClusterName string `json:"clusterName,omitempty"`
 	// SMTPDestinationEmailAddresses is where the alert messages will be sent TO
 	SMTPDestinationEmailAddresses []string `json:"smtp_destination_addresses,omitempty"`
 	// SMTPSenderEmailAddress is the address that will be used to send the alert messages
@@ -61,10 +161,46 @@ type AlertConfiguration struct {
 	SMTPEndpoint string `json:"smtp_endpoint,omitempty"`
 	// SMTPAuthSecretName is the name of the K8s Secret that holds the authentication information
 	SMTPAuthSecretName string `json:"smtp_auth_secret,omitempty"`
-	// SMTPAuthType can be either `none`, `plain`, `login`, or `cram-md5`
+	// SMTPAuthType can be either `none`, `plain`, `login`, `cram-md5`, or `xoauth2`
 	SMTPAuthType string `json:"smtp_auth_type,omitempty"`
 	// SMTPAuthUseSSL can be used to set the use of TLS, default is true
 	SMTPAuthUseSSL *bool `json:"smtp_use_ssl,omitempty"`
 	// SMTPAuthUseSTARTTLS can be used to set the use of STARTTLS, default is true
 	SMTPAuthUseSTARTTLS *bool `json:"smtp_use_starttls,omitempty"`
+	// SMTPTLSSecretName is an optional Secret in the same Namespace holding a `ca.crt` CA bundle to trust, and a `tls.crt`/`tls.key` client certificate for mutual TLS
+	SMTPTLSSecretName string `json:"smtp_tls_secret,omitempty"`
+	// SMTPTLSServerName overrides the server name verified in the SMTP server certificate, defaults to the host in SMTPEndpoint
+	SMTPTLSServerName string `json:"smtp_tls_server_name,omitempty"`
+	// SMTPTLSMinVersion is the minimum TLS version to negotiate with the SMTP server, can be `1.0`, `1.1`, `1.2`, or `1.3`
+	SMTPTLSMinVersion string `json:"smtp_tls_min_version,omitempty"`
+	// SMTPDKIMSecretName is an optional Secret in the same Namespace holding the `private_key` used to DKIM sign the emailed reports
+	SMTPDKIMSecretName string `json:"smtp_dkim_secret,omitempty"`
+	// SMTPDKIMDomain is the DKIM signing domain, defaults to the domain of SMTPSenderEmailAddress
+	SMTPDKIMDomain string `json:"smtp_dkim_domain,omitempty"`
+	// SMTPDKIMSelector is the DNS selector of the DKIM public key, required with SMTPDKIMSecretName
+	SMTPDKIMSelector string `json:"smtp_dkim_selector,omitempty"`
+	// SMTPSubject is an optional template for the email subject, such as `[{{ .ExpiringCerts }}] certs expire within {{ .MostUrgentDaysOut }} days on {{ .ClusterName }}`
+	SMTPSubject string `json:"smtp_subject,omitempty"`
+	// SMTPAttachments is an optional slice of formats to attach the at-risk certificates to the emailed report in, can be `csv` and `json`
+	SMTPAttachments []string `json:"smtp_attachments,omitempty"`
+	// SMTPRouting can be either `none` or `owner` - with `owner` each certificate is reported to the owner annotation on its object, or the owner label on its Namespace, and SMTPDestinationEmailAddresses only receives the rest.  Defaults to none
+	SMTPRouting string `json:"smtp_routing,omitempty"`
+	// SMTPOwnerNamespaceLabel is the Namespace label holding the contact used when a certificate object has no owner annotation
+	SMTPOwnerNamespaceLabel string `json:"smtp_owner_namespace_label,omitempty"`
+	// SMTPOwnerDomain is affixed to owner contacts that are not full email addresses, as label values cannot hold an @
+	SMTPOwnerDomain string `json:"smtp_owner_domain,omitempty"`
+}
+
+// ReportDelivery tracks the delivery of the report to one set of recipients in the report cycle in progress, so a failing route is retried without resending the delivered ones
+type ReportDelivery struct {
+	// Recipients is the comma separated recipients of the route
+	Recipients string `json:"recipients"`
+	// Delivered is when the report was delivered to the recipients, unset until it is
+	Delivered int64 `json:"delivered,omitempty"`
+	// Failures is the number of failed deliveries in a row
+	Failures int `json:"failures,omitempty"`
+	// NextAttempt is the earliest time a failed delivery is retried
+	NextAttempt int64 `json:"nextAttempt,omitempty"`
+	// Error is the error from the last failed delivery
+	Error string `json:"error,omitempty"`
 }
@@ -58,6 +58,11 @@ func (in *AlertConfiguration) DeepCopyInto(out *AlertConfiguration) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.SMTPAttachments != nil {
+		in, out := &in.SMTPAttachments, &out.SMTPAttachments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertConfiguration.
@@ -70,14 +75,81 @@ func (in *AlertConfiguration) DeepCopy() *AlertConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateDetails) DeepCopyInto(out *CertificateDetails) {
+	*out = *in
+	if in.DNSNames != nil {
+		in, out := &in.DNSNames, &out.DNSNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPAddresses != nil {
+		in, out := &in.IPAddresses, &out.IPAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.URIs != nil {
+		in, out := &in.URIs, &out.URIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EmailAddresses != nil {
+		in, out := &in.EmailAddresses, &out.EmailAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyUsage != nil {
+		in, out := &in.KeyUsage, &out.KeyUsage
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtendedKeyUsage != nil {
+		in, out := &in.ExtendedKeyUsage, &out.ExtendedKeyUsage
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateDetails.
+func (in *CertificateDetails) DeepCopy() *CertificateDetails {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateDetails)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateInformation) DeepCopyInto(out *CertificateInformation) {
 	*out = *in
+	in.NotBefore.DeepCopyInto(&out.NotBefore)
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
 	if in.TriggeredDaysOut != nil {
 		in, out := &in.TriggeredDaysOut, &out.TriggeredDaysOut
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.PolicyViolations != nil {
+		in, out := &in.PolicyViolations, &out.PolicyViolations
+		*out = make([]PolicyViolation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Revocation != nil {
+		in, out := &in.Revocation, &out.Revocation
+		*out = new(RevocationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Details != nil {
+		in, out := &in.Details, &out.Details
+		*out = new(CertificateDetails)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kubeconfig != nil {
+		in, out := &in.Kubeconfig, &out.Kubeconfig
+		*out = new(KubeconfigReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateInformation.
@@ -90,6 +162,26 @@ func (in *CertificateInformation) DeepCopy() *CertificateInformation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatePolicy) DeepCopyInto(out *CertificatePolicy) {
+	*out = *in
+	if in.RestrictedWildcardNamespaces != nil {
+		in, out := &in.RestrictedWildcardNamespaces, &out.RestrictedWildcardNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatePolicy.
+func (in *CertificatePolicy) DeepCopy() *CertificatePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateSentinel) DeepCopyInto(out *CertificateSentinel) {
 	*out = *in
@@ -153,7 +245,24 @@ func (in *CertificateSentinelList) DeepCopyObject() runtime.Object {
 func (in *CertificateSentinelSpec) DeepCopyInto(out *CertificateSentinelSpec) {
 	*out = *in
 	in.Target.DeepCopyInto(&out.Target)
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]Target, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Alert.DeepCopyInto(&out.Alert)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PolicyRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.RevocationCheck != nil {
+		in, out := &in.RevocationCheck, &out.RevocationCheck
+		*out = new(RevocationCheck)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateSentinelSpec.
@@ -176,6 +285,16 @@ func (in *CertificateSentinelStatus) DeepCopyInto(out *CertificateSentinelStatus
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ReportDeliveries != nil {
+		in, out := &in.ReportDeliveries, &out.ReportDeliveries
+		*out = make([]ReportDelivery, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedTargets != nil {
+		in, out := &in.FailedTargets, &out.FailedTargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateSentinelStatus.
@@ -188,14 +307,56 @@ func (in *CertificateSentinelStatus) DeepCopy() *CertificateSentinelStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChainVerification) DeepCopyInto(out *ChainVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChainVerification.
+func (in *ChainVerification) DeepCopy() *ChainVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ChainVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSelector) DeepCopyInto(out *DataSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSelector.
+func (in *DataSelector) DeepCopy() *DataSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeystoreInformation) DeepCopyInto(out *KeystoreInformation) {
 	*out = *in
+	in.NotBefore.DeepCopyInto(&out.NotBefore)
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
 	if in.TriggeredDaysOut != nil {
 		in, out := &in.TriggeredDaysOut, &out.TriggeredDaysOut
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.PolicyViolations != nil {
+		in, out := &in.PolicyViolations, &out.PolicyViolations
+		*out = make([]PolicyViolation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Details != nil {
+		in, out := &in.Details, &out.Details
+		*out = new(CertificateDetails)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoreInformation.
@@ -289,6 +450,11 @@ func (in *KeystoreSentinelSpec) DeepCopyInto(out *KeystoreSentinelSpec) {
 	*out = *in
 	in.Target.DeepCopyInto(&out.Target)
 	in.Alert.DeepCopyInto(&out.Alert)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PolicyRule, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoreSentinelSpec.
@@ -311,6 +477,11 @@ func (in *KeystoreSentinelStatus) DeepCopyInto(out *KeystoreSentinelStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ReportDeliveries != nil {
+		in, out := &in.ReportDeliveries, &out.ReportDeliveries
+		*out = make([]ReportDelivery, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoreSentinelStatus.
@@ -331,6 +502,11 @@ func (in *KeystoreTarget) DeepCopyInto(out *KeystoreTarget) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.NamespaceLabels != nil {
 		in, out := &in.NamespaceLabels, &out.NamespaceLabels
 		*out = make([]LabelSelector, len(*in))
@@ -338,6 +514,11 @@ func (in *KeystoreTarget) DeepCopyInto(out *KeystoreTarget) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DataSelectors != nil {
+		in, out := &in.DataSelectors, &out.DataSelectors
+		*out = make([]DataSelector, len(*in))
+		copy(*out, *in)
+	}
 	if in.TargetLabels != nil {
 		in, out := &in.TargetLabels, &out.TargetLabels
 		*out = make([]LabelSelector, len(*in))
@@ -350,6 +531,16 @@ func (in *KeystoreTarget) DeepCopyInto(out *KeystoreTarget) {
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]SeverityThreshold, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(CertificatePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	in.KeystorePassword.DeepCopyInto(&out.KeystorePassword)
 }
 
@@ -363,6 +554,21 @@ func (in *KeystoreTarget) DeepCopy() *KeystoreTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigReference) DeepCopyInto(out *KubeconfigReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigReference.
+func (in *KubeconfigReference) DeepCopy() *KubeconfigReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LabelReference) DeepCopyInto(out *LabelReference) {
 	*out = *in
@@ -405,6 +611,89 @@ func (in *LabelSelector) DeepCopy() *LabelSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRule) DeepCopyInto(out *PolicyRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRule.
+func (in *PolicyRule) DeepCopy() *PolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyViolation) DeepCopyInto(out *PolicyViolation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyViolation.
+func (in *PolicyViolation) DeepCopy() *PolicyViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyViolation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportDelivery) DeepCopyInto(out *ReportDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportDelivery.
+func (in *ReportDelivery) DeepCopy() *ReportDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevocationCheck) DeepCopyInto(out *RevocationCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevocationCheck.
+func (in *RevocationCheck) DeepCopy() *RevocationCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RevocationCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevocationStatus) DeepCopyInto(out *RevocationStatus) {
+	*out = *in
+	if in.RevokedAt != nil {
+		in, out := &in.RevokedAt, &out.RevokedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.NextUpdate != nil {
+		in, out := &in.NextUpdate, &out.NextUpdate
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevocationStatus.
+func (in *RevocationStatus) DeepCopy() *RevocationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RevocationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -420,6 +709,46 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeverityThreshold) DeepCopyInto(out *SeverityThreshold) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeverityThreshold.
+func (in *SeverityThreshold) DeepCopy() *SeverityThreshold {
+	if in == nil {
+		return nil
+	}
+	out := new(SeverityThreshold)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSProbe) DeepCopyInto(out *TLSProbe) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSProbe.
+func (in *TLSProbe) DeepCopy() *TLSProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Target) DeepCopyInto(out *Target) {
 	*out = *in
@@ -428,6 +757,11 @@ func (in *Target) DeepCopyInto(out *Target) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.NamespaceLabels != nil {
 		in, out := &in.NamespaceLabels, &out.NamespaceLabels
 		*out = make([]LabelSelector, len(*in))
@@ -435,6 +769,12 @@ func (in *Target) DeepCopyInto(out *Target) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DataSelectors != nil {
+		in, out := &in.DataSelectors, &out.DataSelectors
+		*out = make([]DataSelector, len(*in))
+		copy(*out, *in)
+	}
+	in.TLSProbe.DeepCopyInto(&out.TLSProbe)
 	if in.TargetLabels != nil {
 		in, out := &in.TargetLabels, &out.TargetLabels
 		*out = make([]LabelSelector, len(*in))
@@ -447,6 +787,21 @@ func (in *Target) DeepCopyInto(out *Target) {
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]SeverityThreshold, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(CertificatePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChainVerification != nil {
+		in, out := &in.ChainVerification, &out.ChainVerification
+		*out = new(ChainVerification)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
@@ -18,12 +18,15 @@ package config
 
 import (
 	"context"
-	"reflect"
+	"crypto/x509"
+	"encoding/pem"
+	goerrors "errors"
 	"strconv"
 	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
@@ -60,6 +63,12 @@ var _ reconcile.Reconciler = &CertificateSentinelReconciler{}
 var lggr = log.Log.WithName("certificate-sentinel-controller")
 var SetLogLevel int
 
+// errInvalidTargetKind is returned when a Target Kind is not supported and has no DataSelectors
+var errInvalidTargetKind = goerrors.New("invalid target kind")
+
+// errMissingServiceAccountToken is returned when a Target ServiceAccount has no API Token type Secret to build a client from
+var errMissingServiceAccountToken = goerrors.New("no API Token type Secret found in ServiceAccount")
+
 //===========================================================================================
 // INIT FUNC
 //===========================================================================================
@@ -78,6 +87,11 @@ func init() {
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations;mutatingwebhookconfigurations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiregistration.k8s.io,resources=apiservices,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
 
 //===========================================================================================
 // RECONCILE
@@ -124,48 +138,176 @@ func (r *CertificateSentinelReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Set default vars
 	scanningInterval := defaults.SetDefaultInt(defaults.ScanningInterval, certificateSentinel.Spec.ScanningInterval)
-	targetName := certificateSentinel.Spec.Target.TargetName
+	rules := CompileSentinelRules(certificateSentinel.Spec.Rules, lggr)
+	revocation := NewRevocationChecker(certificateSentinel.Spec.RevocationCheck, lggr)
+
+	CertHashList := []string{}
+	expiredCertificateCount := 0
+
+	// Loop through the targets, merging their discoveries into one status and one report
+	// A failing target is skipped so the discoveries of the other targets are still reported
+	var failedTargets []string
+	for _, target := range GetCertificateSentinelTargets(certificateSentinel.Spec) {
+		targetExpiredCount, err := r.scanTarget(target, rules, revocation, certificateSentinel.Namespace, clusterEndpoint, apiPath, scanningInterval, &CertHashList, &statusLists)
+		if err != nil {
+			lggr.Error(err, "Failed to scan target "+target.TargetName+", keeping its previous certificates!")
+			failedTargets = append(failedTargets, target.TargetName)
+			continue
+		}
+		expiredCertificateCount += targetExpiredCount
+	}
+
+	// Keep the certificates the failed targets found on their last successful scan
+	expiredCertificateCount += keepFailedTargetCertificates(certificateSentinel.Status.DiscoveredCertificates, failedTargets, &statusLists)
+
+	// Set updater check vars
+	oldStatus := *certificateSentinel.Status.DeepCopy()
+
+	// Merge the Certificates into the .status of the CertificateSentinel object
+	certificateSentinel.Status.DiscoveredCertificates = statusLists.DiscoveredCertificates
+	certificateSentinel.Status.ExpiringCertificates = expiredCertificateCount
+	certificateSentinel.Status.FailedTargets = failedTargets
+
+	// Process reports if needed, only if there are new certificates at risk
+	if hasCertificatesAtRisk(certificateSentinel.Status.DiscoveredCertificates) {
+		lastReportSent, reportDeliveries, reportAttempted, reportErr := processReport(*certificateSentinel, lggr, r.Client)
+		certificateSentinel.Status.ReportDeliveries = reportDeliveries
+		certificateSentinel.Status.LastReportSent = lastReportSent
+		if reportAttempted {
+			certificateSentinel.Status.LastReportAttempt = time.Now().Unix()
+			certificateSentinel.Status.LastReportError = ""
+			if reportErr != nil {
+				certificateSentinel.Status.LastReportError = reportErr.Error()
+			}
+		}
+	}
 
-	serviceAccount := certificateSentinel.Spec.Target.ServiceAccount
-	targetKind := certificateSentinel.Spec.Target.Kind
-	targetAPIVersion := certificateSentinel.Spec.Target.APIVersion
-	targetDaysOut := certificateSentinel.Spec.Target.DaysOut
+	// Check the difference in structs
+	if certificateSentinelStatusChanged(oldStatus, certificateSentinel.Status) {
+		err = r.Status().Update(ctx, certificateSentinel)
+		if err != nil {
+			lggr.Error(err, "Failed to update CertificateSentinel status")
+			return ctrl.Result{}, err
+		}
+	}
+	LogWithLevel("Found "+strconv.Itoa(len(certificateSentinel.Status.DiscoveredCertificates))+" Certificates, "+strconv.Itoa(expiredCertificateCount)+" of which are at risk of expiring", 2, lggr)
+
+	// Reconcile successful - don't requeue
+	// return ctrl.Result{}, nil
+	// Reconcile failed due to error - requeue
+	// return ctrl.Result{}, err
+	// Requeue for any reason other than an error
+	// return ctrl.Result{Requeue: true}, nil
+
+	// Reconcile for any reason other than an error after 5 seconds
+	lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
+	return ctrl.Result{RequeueAfter: time.Second * time.Duration(scanningInterval)}, nil
+}
+
+// certificateSentinelStatusChanged compares the .status semantically, the times read back from the API server are in Local time while freshly scanned ones are in UTC
+func certificateSentinelStatusChanged(oldStatus configv1.CertificateSentinelStatus, newStatus configv1.CertificateSentinelStatus) bool {
+	return !equality.Semantic.DeepEqual(oldStatus, newStatus)
+}
+
+// GetCertificateSentinelTargets returns the single Target merged with the slice of Targets defined on a CertificateSentinel
+func GetCertificateSentinelTargets(spec configv1.CertificateSentinelSpec) []configv1.Target {
+	var targets []configv1.Target
+	if spec.Target.Kind != "" {
+		targets = append(targets, spec.Target)
+	}
+	return append(targets, spec.Targets...)
+}
+
+// keepFailedTargetCertificates carries the certificates of the failed targets over from the previous .status, returning the number of them at risk of expiring
+func keepFailedTargetCertificates(previous []configv1.CertificateInformation, failedTargets []string, statusLists *configv1.CertificateSentinelStatus) int {
+	expiredCertificateCount := 0
+	for _, certInfo := range previous {
+		if !defaults.ContainsString(failedTargets, certInfo.TargetName) {
+			continue
+		}
+		if len(certInfo.TriggeredDaysOut) > 0 {
+			expiredCertificateCount++
+		}
+		statusLists.DiscoveredCertificates = append(statusLists.DiscoveredCertificates, certInfo)
+	}
+	return expiredCertificateCount
+}
+
+// scanTarget connects to the cluster as the Target ServiceAccount and adds the certificates it discovers into the .status lists, returning the number of them at risk of expiring
+func (r *CertificateSentinelReconciler) scanTarget(target configv1.Target, rules []helpers.PolicyRuleProgram, revocation *helpers.RevocationChecker, sentinelNamespace string, clusterEndpoint string, apiPath string, scanningInterval int, certHashList *[]string, statusLists *configv1.CertificateSentinelStatus) (int, error) {
+	targetName := target.TargetName
+
+	serviceAccount := target.ServiceAccount
+	targetKind := target.Kind
+	targetAPIVersion := target.APIVersion
+	targetDaysOut := target.DaysOut
 	timeOut := DaysOutToTimeOut(targetDaysOut)
+	options := certificateScanOptions{
+		severities:     TargetSeverities(target.Severities),
+		includeDetails: target.IncludeDetails,
+		policy:         TargetPolicy(target.Policy),
+		rules:          rules,
+		revocation:     revocation,
+	}
 
-	targetLabels := certificateSentinel.Spec.Target.TargetLabels
-	targetNamespaceLabels := certificateSentinel.Spec.Target.NamespaceLabels
+	// Load the trust anchors once per target, a trust bundle that can not be loaded disables chain verification rather than flagging every chain against the wrong roots
+	if target.ChainVerification != nil {
+		roots, err := LoadTrustBundle(*target.ChainVerification, sentinelNamespace, r.Client)
+		if err != nil {
+			lggr.Error(err, "Failed to load the chain verification trust bundle, skipping chain verification for target "+targetName+"!")
+		} else {
+			options.verifyChains = true
+			options.chainRoots = roots
+		}
+	}
+
+	targetLabels := target.TargetLabels
+	targetNamespaceLabels := target.NamespaceLabels
 
 	targetLabelSelector, targetNamespaceLabelSelector := SetupLabelSelectors(targetLabels, targetNamespaceLabels, LggrK)
 
-	//effectiveNamespaces := []string{}
-	CertHashList := []string{}
 	expiredCertificateCount := 0
 
 	LogWithLevel("Processing CertificateSentinel target: "+targetName, 2, lggr)
 
+	// Explicit host:port endpoints are not tied to a Namespace and never use the API, so they are probed once without the ServiceAccount
+	if targetKind == "TLSEndpoint" {
+		for _, address := range target.TLSProbe.Endpoints {
+			address = strings.TrimSpace(address)
+			LogWithLevel("Probing TLS endpoint "+address, 3, lggr)
+			certs, err := probeTLSEndpoint(address, target.TLSProbe)
+			if err != nil {
+				lggr.Error(err, "Failed to probe TLS endpoint "+address)
+				continue
+			}
+
+			// Loop through the presented certificate chain
+			expiredCertificateCount += processDiscoveredCertificates(certs, timeOut, options, configv1.CertificateInformation{Name: address, DataKey: address, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion}, certHashList, statusLists)
+		}
+		return expiredCertificateCount, nil
+	}
+
 	// Get ServiceAccount
 	LogWithLevel("Using ServiceAccount: "+serviceAccount, 2, lggr)
-	targetServiceAccount, _ := GetServiceAccount(serviceAccount, certificateSentinel.Namespace, r.Client)
+	targetServiceAccount, _ := GetServiceAccount(serviceAccount, sentinelNamespace, r.Client)
 	var serviceAccountSecretName string
 	targetServiceAccountSecret := &corev1.Secret{}
 
 	// Find the right secret
 	for _, em := range targetServiceAccount.Secrets {
-		secret, _ := GetSecret(em.Name, certificateSentinel.Namespace, r.Client)
+		secret, _ := GetSecret(em.Name, sentinelNamespace, r.Client)
 		if secret.Type == "kubernetes.io/service-account-token" {
 			// Get Secret
 			serviceAccountSecretName = em.Name
 			LogWithLevel("Using Secret: "+serviceAccountSecretName, 2, lggr)
-			targetServiceAccountSecret, _ = GetSecret(serviceAccountSecretName, certificateSentinel.Namespace, r.Client)
+			targetServiceAccountSecret, _ = GetSecret(serviceAccountSecretName, sentinelNamespace, r.Client)
 		}
 	}
 
 	// We didn't find a Secret to work against the API and thus can't create a new client
 	if serviceAccountSecretName == "" {
-		lggr.Error(err, "Failed to find API Token type Secret in ServiceAccount!")
-		lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-		time.Sleep(time.Second * time.Duration(scanningInterval))
-		return ctrl.Result{}, err
+		lggr.Error(errMissingServiceAccountToken, "Failed to find API Token type Secret in ServiceAccount!")
+		return expiredCertificateCount, errMissingServiceAccountToken
 	}
 
 	// Set up new client config
@@ -182,45 +324,28 @@ func (r *CertificateSentinelReconciler) Reconcile(ctx context.Context, req ctrl.
 	cl, err := client.New(newConfig, client.Options{})
 	if err != nil {
 		lggr.Error(err, "Failed to create client")
-		lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-		time.Sleep(time.Second * time.Duration(scanningInterval))
-		return ctrl.Result{}, err
+		return expiredCertificateCount, err
 	}
 
-	/*
-		// Loop through target namespaces
-		for _, elem := range certificateSentinel.Spec.Target.Namespaces {
-			namespaceList := &corev1.NamespaceList{}
-			ns := strings.TrimSpace(elem)
-			activeNamespace := ns
-			activeNamespaceDisplayName := ns
-
-			if ns == "*" {
-				activeNamespace = ""
-				activeNamespaceDisplayName = "*"
-			}
-
-			LogWithLevel("Querying for namespace/"+activeNamespaceDisplayName+" with sa/"+serviceAccount, 3, lggr)
-			// Get Namespace with the cached context
-			namespaceListOptions := &client.ListOptions{Namespace: activeNamespace, LabelSelector: targetNamespaceLabelSelector}
-			err = cl.List(context.Background(), namespaceList, namespaceListOptions)
-			if err != nil {
-				lggr.Error(err, "Failed to list namespace in cluster!")
-				lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-				time.Sleep(time.Second * time.Duration(scanningInterval))
-				return ctrl.Result{}, err
-			}
-			// Loop through NamespaceList, create the effectiveNamespaces slice
-			for _, el := range namespaceList.Items {
-				if !defaults.ContainsString(effectiveNamespaces, el.Name) {
-					LogWithLevel("Adding ns/"+el.Name+" to scope", 3, lggr)
-					effectiveNamespaces = append(effectiveNamespaces, el.Name)
-				}
-			}
+	effectiveNamespaces := []string{}
+	if IsCABundleKind(targetKind) {
+		// caBundle carrying objects are cluster-scoped, so they are scanned once instead of per Namespace
+		caBundleSources, err := GetCABundleSources(targetKind, targetLabelSelector, cl)
+		if err != nil {
+			lggr.Error(err, "Failed to list "+targetKind+" objects in cluster")
 		}
-	*/
 
-	effectiveNamespaces, _ := SetupNamespaceSlice(certificateSentinel.Spec.Target.Namespaces, cl, lggr, serviceAccount, targetNamespaceLabelSelector, scanningInterval)
+		// Loop through the caBundles
+		for _, e := range caBundleSources {
+			LogWithLevel("CA BUNDLE FOUND! - "+targetKind+"/"+e.Name+" - key:"+e.DataKey, 3, lggr)
+			certs, _ := helpers.DecodeCertificateBytes(e.CABundle, lggr)
+
+			// Loop through the current collection of certificates
+			expiredCertificateCount += processDiscoveredCertificates(certs, timeOut, options, configv1.CertificateInformation{Name: e.Name, DataKey: e.DataKey, TargetName: targetName, Kind: targetKind, APIVersion: e.APIVersion}, certHashList, statusLists)
+		}
+	} else {
+		effectiveNamespaces, _ = SetupNamespaceSlice(target.Namespaces, NamespaceExclusions(target.ExcludeNamespaces, target.ExcludeSystemNamespaces), cl, lggr, serviceAccount, targetNamespaceLabelSelector, scanningInterval)
+	}
 
 	// Loop through the namespaces in scope for this target
 	for _, el := range effectiveNamespaces {
@@ -239,12 +364,21 @@ func (r *CertificateSentinelReconciler) Reconcile(ctx context.Context, req ctrl.
 			for _, e := range secretList.Items {
 				secretType := string(e.Type)
 				if secretType == string(corev1.SecretTypeOpaque) || secretType == string(corev1.SecretTypeTLS) {
+					objectAnnotations := GetObjectAnnotations(e.Annotations, "secret/"+e.Name+" in namespace/"+el, lggr)
+					if objectAnnotations.Ignore {
+						LogWithLevel("Ignoring annotated secret/"+e.Name+" in namespace/"+el, 3, lggr)
+						continue
+					}
+					objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
 					LogWithLevel("Getting secret/"+e.Name+" in namespace/"+el+" (type="+secretType+")", 3, lggr)
 
 					secretItem, _ := GetSecret(string(e.Name), el, cl)
 
 					// Get the actual secret data
 					for k, s := range secretItem.Data {
+						if objectAnnotations.IgnoresKey(k) {
+							continue
+						}
 						// Store the secret as a base64 decoded string from the byte slice
 						sDataStr := string(s)
 
@@ -252,38 +386,29 @@ func (r *CertificateSentinelReconciler) Reconcile(ctx context.Context, req ctrl.
 						if strings.Contains(sDataStr, "-----BEGIN CERTIFICATE-----") {
 							LogWithLevel("CERTIFICATE FOUND! - ns/"+el+" - secret/"+string(e.Name)+" - key:"+k, 3, lggr)
 							certs, _ := helpers.DecodeCertificateBytes(s, lggr)
+							// Find the private key stored with the certificate so the pair can be checked
+							keyName, privateKey := helpers.MatchingPrivateKey(secretItem.Data, k)
+							if privateKey != nil {
+								LogWithLevel("PRIVATE KEY FOUND! - ns/"+el+" - secret/"+string(e.Name)+" - key:"+keyName, 3, lggr)
+							}
 
-							// Loop through the current collection of certificates
-							for _, cert := range certs {
-								// Check to see if this has already been added
-								sha_str := createUniqueCertificateChecksum(targetKind+"-"+el+"-"+e.Name+"-"+cert.Subject.CommonName+"-"+cert.Issuer.CommonName, cert)
-
-								if defaults.ContainsString(CertHashList, sha_str) {
-									// Skipping Certificate
-									LogWithLevel("Already found "+sha_str, 3, lggr)
-								} else {
-									// Add + Process
-									LogWithLevel("Adding "+sha_str, 3, lggr)
-									CertHashList = append(CertHashList, sha_str)
-
-									discovered, messages := helpers.ParseCertificateIntoObjects(cert, timeOut, el, e.Name, k, targetKind, targetAPIVersion)
-									// Loop through passed messages for log level 3
-									for _, m := range messages {
-										LogWithLevel(m, 3, lggr)
-									}
-
-									// Add decoded certificate to DiscoveredCertificates
-									if len(discovered) != 0 {
-										for _, iv := range discovered {
-											if len(iv.TriggeredDaysOut) > 0 {
-												expiredCertificateCount++
-											}
-											statusLists.DiscoveredCertificates = append(statusLists.DiscoveredCertificates, iv)
-										}
-									}
-								}
+							// Loop through the current collection of certificates, checking the first against the private key
+							keyPairOptions := options
+							keyPairOptions.privateKey = privateKey
+							expiredCertificateCount += processDiscoveredCertificates(certs, objectTimeOut, keyPairOptions, configv1.CertificateInformation{Namespace: el, Name: e.Name, DataKey: k, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion, Owner: objectAnnotations.Owner}, certHashList, statusLists)
+						} else if helpers.IsKubeconfig(s) {
+							// See if this is a kubeconfig file with embedded cluster CA and user client certificates
+							kubeconfigCerts, err := helpers.DecodeKubeconfigCertificates(s, lggr)
+							if err != nil {
+								LogWithLevel("Failed to parse kubeconfig in ns/"+el+" - secret/"+string(e.Name)+" - key:"+k+" - "+err.Error(), 3, lggr)
 							}
 
+							// Loop through the embedded certificates of each cluster and user
+							for _, kc := range kubeconfigCerts {
+								LogWithLevel("KUBECONFIG CERTIFICATE FOUND! - ns/"+el+" - secret/"+string(e.Name)+" - key:"+k+" - "+kc.Field, 3, lggr)
+								kubeconfigRef := kc.Reference
+								expiredCertificateCount += processDiscoveredCertificates(kc.Certificates, objectTimeOut, options, configv1.CertificateInformation{Namespace: el, Name: e.Name, DataKey: k, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion, Owner: objectAnnotations.Owner, Kubeconfig: &kubeconfigRef}, certHashList, statusLists)
+							}
 						}
 
 					}
@@ -301,100 +426,213 @@ func (r *CertificateSentinelReconciler) Reconcile(ctx context.Context, req ctrl.
 
 			// Loop through ConfigMaps
 			for _, e := range configMapList.Items {
+				objectAnnotations := GetObjectAnnotations(e.Annotations, "configmap/"+e.Name+" in namespace/"+el, lggr)
+				if objectAnnotations.Ignore {
+					LogWithLevel("Ignoring annotated configmap/"+e.Name+" in namespace/"+el, 3, lggr)
+					continue
+				}
+				objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
 				LogWithLevel("Getting configmap/"+e.Name+" in namespace/"+el, 3, lggr)
 				configMapItem, _ := GetConfigMap(string(e.Name), el, cl)
 
 				// Loop through the actual ConfigMap data
 				for k, cm := range configMapItem.Data {
+					if objectAnnotations.IgnoresKey(k) {
+						continue
+					}
 					// See if this contains text about a Certificate
 					if strings.Contains(string(cm), "-----BEGIN CERTIFICATE-----") {
 						LogWithLevel("CERTIFICATE FOUND! - ns/"+el+" - configmap/"+string(e.Name)+" - key:"+k, 3, lggr)
 						certs, _ := helpers.DecodeCertificateBytes([]byte(cm), lggr)
 
 						// Loop through the current collection of certificates
-						for _, cert := range certs {
-							// Check to see if this has already been added
-							sha_str := createUniqueCertificateChecksum(targetKind+"-"+el+"-"+e.Name+"-"+cert.Subject.CommonName+"-"+cert.Issuer.CommonName, cert)
-
-							if defaults.ContainsString(CertHashList, sha_str) {
-								// Skipping Certificate
-								LogWithLevel("Already found "+sha_str, 3, lggr)
-							} else {
-								// Add + Process
-								LogWithLevel("Adding "+sha_str, 3, lggr)
-								CertHashList = append(CertHashList, sha_str)
-
-								discovered, messages := helpers.ParseCertificateIntoObjects(cert, timeOut, el, e.Name, k, targetKind, targetAPIVersion)
-								// Loop through passed messages for log level 3
-								for _, m := range messages {
-									LogWithLevel(m, 3, lggr)
-								}
-
-								// Add decoded certificate to DiscoveredCertificates
-								if len(discovered) != 0 {
-									for _, iv := range discovered {
-										if len(iv.TriggeredDaysOut) > 0 {
-											expiredCertificateCount++
-										}
-										statusLists.DiscoveredCertificates = append(statusLists.DiscoveredCertificates, iv)
-									}
-								}
-							}
-						}
+						expiredCertificateCount += processDiscoveredCertificates(certs, objectTimeOut, options, configv1.CertificateInformation{Namespace: el, Name: e.Name, DataKey: k, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion, Owner: objectAnnotations.Owner}, certHashList, statusLists)
+					}
+				}
+			}
+		//=========================== SERVICES
+		case "Service":
+			LogWithLevel("Checking for access to Service in ns/"+el, 3, lggr)
+			// Get the list of Services in this namespace
+			serviceList := &corev1.ServiceList{}
+			err = cl.List(context.Background(), serviceList, targetListOptions)
+			if err != nil {
+				lggr.Error(err, "Failed to list Services in ns/"+el)
+			}
+
+			// Loop through Services and dial their TLS ports
+			for _, e := range serviceList.Items {
+				objectAnnotations := GetObjectAnnotations(e.Annotations, "service/"+e.Name+" in namespace/"+el, lggr)
+				if objectAnnotations.Ignore {
+					LogWithLevel("Ignoring annotated service/"+e.Name+" in namespace/"+el, 3, lggr)
+					continue
+				}
+				objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
 
+				for _, address := range GetServiceProbeAddresses(e, target.TLSProbe.Ports) {
+					if objectAnnotations.IgnoresKey(address) {
+						continue
 					}
+					LogWithLevel("Probing TLS endpoint "+address+" for service/"+e.Name+" in namespace/"+el, 3, lggr)
+					certs, err := probeTLSEndpoint(address, target.TLSProbe)
+					if err != nil {
+						lggr.Error(err, "Failed to probe TLS endpoint "+address)
+						continue
+					}
+
+					// Loop through the presented certificate chain
+					expiredCertificateCount += processDiscoveredCertificates(certs, objectTimeOut, options, configv1.CertificateInformation{Namespace: el, Name: e.Name, DataKey: address, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion, Owner: objectAnnotations.Owner}, certHashList, statusLists)
 				}
 			}
-		//=========================== DEFAULT - INVALID KIND
+		//=========================== DEFAULT - GENERIC KIND WITH DATA SELECTORS
 		default:
+			if len(target.DataSelectors) > 0 {
+				LogWithLevel("Checking for access to "+targetKind+" in ns/"+el, 3, lggr)
+				// Get the list of objects of this Kind in this namespace
+				objectList, err := GetUnstructuredList(targetAPIVersion, targetKind, targetListOptions, cl)
+				if err != nil {
+					lggr.Error(err, "Failed to list "+targetKind+" in ns/"+el)
+				}
+
+				// Loop through the objects
+				for _, e := range objectList.Items {
+					objectAnnotations := GetObjectAnnotations(e.GetAnnotations(), strings.ToLower(targetKind)+"/"+e.GetName()+" in namespace/"+el, lggr)
+					if objectAnnotations.Ignore {
+						LogWithLevel("Ignoring annotated "+strings.ToLower(targetKind)+"/"+e.GetName()+" in namespace/"+el, 3, lggr)
+						continue
+					}
+					objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
+
+					// Loop through the data located by the DataSelectors
+					for _, sd := range SelectDataFromObject(e, target.DataSelectors) {
+						if objectAnnotations.IgnoresKey(sd.DataKey) {
+							continue
+						}
+						// See if this contains text about a Certificate
+						if strings.Contains(string(sd.Data), "-----BEGIN CERTIFICATE-----") {
+							LogWithLevel("CERTIFICATE FOUND! - ns/"+el+" - "+strings.ToLower(targetKind)+"/"+e.GetName()+" - key:"+sd.DataKey, 3, lggr)
+							certs, _ := helpers.DecodeCertificateBytes(sd.Data, lggr)
+
+							// Loop through the current collection of certificates
+							expiredCertificateCount += processDiscoveredCertificates(certs, objectTimeOut, options, configv1.CertificateInformation{Namespace: el, Name: e.GetName(), DataKey: sd.DataKey, TargetName: targetName, Kind: targetKind, APIVersion: targetAPIVersion, Owner: objectAnnotations.Owner}, certHashList, statusLists)
+						}
+					}
+				}
+				break
+			}
+
 			// Unsupported Object Kind
 			lggr.Info("Invalid Target Kind!")
-			lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-			time.Sleep(time.Second * time.Duration(scanningInterval))
-			return ctrl.Result{}, nil
+			return expiredCertificateCount, errInvalidTargetKind
 		}
 	}
-	//}
 
-	// Set updater check vars
-	statusChanged := true
-	oldDiscoveredCertificates := certificateSentinel.Status.DiscoveredCertificates
-	oldLastReportSent := certificateSentinel.Status.LastReportSent
-	oldExpiringCertificates := certificateSentinel.Status.ExpiringCertificates
+	return expiredCertificateCount, nil
+}
 
-	// Merge the Certificates into the .status of the CertificateSentinel object
-	certificateSentinel.Status.DiscoveredCertificates = statusLists.DiscoveredCertificates
-	certificateSentinel.Status.ExpiringCertificates = expiredCertificateCount
+// certificateScanOptions are the checks of a target applied to each certificate it discovers, built once per target in scanTarget
+type certificateScanOptions struct {
+	severities     []configv1.SeverityThreshold
+	includeDetails bool
+	policy         *configv1.CertificatePolicy
+	rules          []helpers.PolicyRuleProgram
+	verifyChains   bool
+	chainRoots     *x509.CertPool
+	// privateKey is the private key stored with the certificates of a Secret data key, nil elsewhere
+	privateKey *pem.Block
+	revocation *helpers.RevocationChecker
+}
 
-	// Process reports if needed, only if there are new certificates at risk
-	if expiredCertificateCount > 0 {
-		certificateSentinel.Status.LastReportSent = processReport(*certificateSentinel, lggr, r.Client)
+// certificateChecksumSeed identifies where a certificate was found for de-duplication, including the kubeconfig context, cluster, and user so a certificate shared by several kubeconfig entries is listed for each
+func certificateChecksumSeed(source configv1.CertificateInformation, cert *x509.Certificate) string {
+	seed := source.Kind + "-" + source.Namespace + "-" + source.Name + "-" + cert.Subject.CommonName + "-" + cert.Issuer.CommonName
+	if source.Kubeconfig != nil {
+		seed += "-" + source.Kubeconfig.Context + "-" + source.Kubeconfig.Cluster + "-" + source.Kubeconfig.User
 	}
+	return seed
+}
 
-	// Check the difference in structs
-	if reflect.DeepEqual(oldDiscoveredCertificates, certificateSentinel.Status.DiscoveredCertificates) && oldLastReportSent == certificateSentinel.Status.LastReportSent && oldExpiringCertificates == certificateSentinel.Status.ExpiringCertificates {
-		statusChanged = false
+// processDiscoveredCertificates adds any certificates not yet seen into the .status list and returns the number of them at risk of expiring
+// The source CertificateInformation provides where the certificates were found and is copied onto every discovered entry
+// A privateKey found with the certificates is checked against the first certificate, which Kubernetes TLS Secrets require to be the one the key belongs to
+// Leaf certificates are checked for revocation when the sentinel has a revocation checker
+func processDiscoveredCertificates(certs []*x509.Certificate, timeOut []configv1.TimeSlice, options certificateScanOptions, source configv1.CertificateInformation, certHashList *[]string, statusLists *configv1.CertificateSentinelStatus) int {
+	expiredCertificateCount := 0
+
+	// Chain findings are reported on the leaf certificate of the bundle
+	leafIndex := -1
+	var chainViolations []configv1.PolicyViolation
+	if options.verifyChains {
+		leafIndex, chainViolations = helpers.VerifyCertificateChain(certs, options.chainRoots, time.Now())
 	}
 
-	if statusChanged {
-		err = r.Status().Update(ctx, certificateSentinel)
-		if err != nil {
-			lggr.Error(err, "Failed to update CertificateSentinel status")
-			return ctrl.Result{}, err
+	for i, cert := range certs {
+		// Check to see if this has already been added
+		sha_str := createUniqueCertificateChecksum(certificateChecksumSeed(source, cert), cert)
+
+		if defaults.ContainsString(*certHashList, sha_str) {
+			// Skipping Certificate
+			LogWithLevel("Already found "+sha_str, 3, lggr)
+		} else {
+			// Add + Process
+			LogWithLevel("Adding "+sha_str, 3, lggr)
+			*certHashList = append(*certHashList, sha_str)
+
+			discovered, messages := helpers.ParseCertificateIntoObjects(cert, timeOut, source.Namespace, source.Name, source.DataKey, source.Kind, source.APIVersion)
+			// Loop through passed messages for log level 3
+			for _, m := range messages {
+				LogWithLevel(m, 3, lggr)
+			}
+
+			// Add decoded certificate to DiscoveredCertificates
+			for _, iv := range discovered {
+				iv.TargetName = source.TargetName
+				iv.Owner = source.Owner
+				iv.Kubeconfig = source.Kubeconfig
+				iv.Severity = helpers.CertificateSeverity(cert.NotAfter, time.Now(), options.severities)
+				if options.includeDetails {
+					iv.Details = helpers.ParseCertificateDetails(cert)
+				}
+				if options.policy != nil {
+					iv.PolicyViolations = helpers.EvaluateCertificatePolicy(cert, source.Namespace, *options.policy)
+				}
+				if len(options.rules) > 0 {
+					ruleViolations, errs := helpers.EvaluatePolicyRules(options.rules, helpers.CertificateRuleVariables(cert, source.Namespace, source.Name, source.Kind, source.DataKey, time.Now()))
+					for _, err := range errs {
+						LogWithLevel("Failed to evaluate policy "+err.Error(), 2, lggr)
+					}
+					iv.PolicyViolations = append(iv.PolicyViolations, ruleViolations...)
+				}
+				if i == leafIndex {
+					iv.PolicyViolations = append(iv.PolicyViolations, chainViolations...)
+				}
+				if i == 0 && options.privateKey != nil {
+					minimumRSAKeySize := defaults.PolicyMinimumRSAKeySize
+					if options.policy != nil {
+						minimumRSAKeySize = options.policy.MinimumRSAKeySize
+					}
+					iv.PolicyViolations = append(iv.PolicyViolations, helpers.ValidateKeyPair(cert, options.privateKey, minimumRSAKeySize)...)
+				}
+				if options.revocation != nil && !cert.IsCA && helpers.HasRevocationEndpoints(cert) {
+					result, err := options.revocation.CheckRevocation(cert, certs, time.Now())
+					if err != nil {
+						LogWithLevel("Failed to check revocation of "+cert.Subject.CommonName+" - "+err.Error(), 2, lggr)
+					}
+					iv.Revocation = RevocationStatusFromResult(result, err)
+					if result.Status == helpers.RevocationStatusRevoked {
+						iv.Revoked = true
+						iv.Severity = helpers.SeverityRevoked
+					}
+				}
+				if len(iv.TriggeredDaysOut) > 0 {
+					expiredCertificateCount++
+				}
+				statusLists.DiscoveredCertificates = append(statusLists.DiscoveredCertificates, iv)
+			}
 		}
 	}
-	LogWithLevel("Found "+strconv.Itoa(len(certificateSentinel.Status.DiscoveredCertificates))+" Certificates, "+strconv.Itoa(expiredCertificateCount)+" of which are at risk of expiring", 2, lggr)
-
-	// Reconcile successful - don't requeue
-	// return ctrl.Result{}, nil
-	// Reconcile failed due to error - requeue
-	// return ctrl.Result{}, err
-	// Requeue for any reason other than an error
-	// return ctrl.Result{Requeue: true}, nil
 
-	// Reconcile for any reason other than an error after 5 seconds
-	lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-	return ctrl.Result{RequeueAfter: time.Second * time.Duration(scanningInterval)}, nil
+	return expiredCertificateCount
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/base64"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CABundleSource is a single caBundle field found on a cluster-scoped object
+type CABundleSource struct {
+	// Name is the name of the object holding the caBundle
+	Name string
+	// DataKey is the path to the caBundle field inside of the object
+	DataKey string
+	// APIVersion is the apiVersion of the object holding the caBundle
+	APIVersion string
+	// CABundle is the PEM encoded bundle of certificates
+	CABundle []byte
+}
+
+// caBundleKinds maps the cluster-scoped kinds that carry a caBundle to their apiVersion
+var caBundleKinds = map[string]string{
+	"ValidatingWebhookConfiguration": "admissionregistration.k8s.io/v1",
+	"MutatingWebhookConfiguration":   "admissionregistration.k8s.io/v1",
+	"APIService":                     "apiregistration.k8s.io/v1",
+	"CustomResourceDefinition":       "apiextensions.k8s.io/v1",
+}
+
+// IsCABundleKind returns true if the target Kind is one of the cluster-scoped objects that carry a caBundle
+func IsCABundleKind(kind string) bool {
+	_, ok := caBundleKinds[kind]
+	return ok
+}
+
+// GetCABundleSources lists the cluster-scoped objects of the given kind and returns every caBundle set on them
+func GetCABundleSources(kind string, labelSelector labels.Selector, clnt client.Client) ([]CABundleSource, error) {
+	var sources []CABundleSource
+	apiVersion := caBundleKinds[kind]
+	listOptions := &client.ListOptions{LabelSelector: labelSelector}
+
+	switch kind {
+	//=========================== VALIDATING WEBHOOKS
+	case "ValidatingWebhookConfiguration":
+		webhookList := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+		err := clnt.List(context.Background(), webhookList, listOptions)
+		if err != nil {
+			return sources, err
+		}
+		for _, e := range webhookList.Items {
+			for _, wh := range e.Webhooks {
+				if len(wh.ClientConfig.CABundle) > 0 {
+					sources = append(sources, CABundleSource{Name: e.Name, DataKey: "webhooks[" + wh.Name + "].clientConfig.caBundle", APIVersion: apiVersion, CABundle: wh.ClientConfig.CABundle})
+				}
+			}
+		}
+	//=========================== MUTATING WEBHOOKS
+	case "MutatingWebhookConfiguration":
+		webhookList := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+		err := clnt.List(context.Background(), webhookList, listOptions)
+		if err != nil {
+			return sources, err
+		}
+		for _, e := range webhookList.Items {
+			for _, wh := range e.Webhooks {
+				if len(wh.ClientConfig.CABundle) > 0 {
+					sources = append(sources, CABundleSource{Name: e.Name, DataKey: "webhooks[" + wh.Name + "].clientConfig.caBundle", APIVersion: apiVersion, CABundle: wh.ClientConfig.CABundle})
+				}
+			}
+		}
+	//=========================== API SERVICES
+	case "APIService":
+		return getUnstructuredCABundleSources(kind, apiVersion, []string{"spec", "caBundle"}, "spec.caBundle", listOptions, clnt)
+	//=========================== CRD CONVERSION WEBHOOKS
+	case "CustomResourceDefinition":
+		return getUnstructuredCABundleSources(kind, apiVersion, []string{"spec", "conversion", "webhook", "clientConfig", "caBundle"}, "spec.conversion.webhook.clientConfig.caBundle", listOptions, clnt)
+	}
+
+	return sources, nil
+}
+
+// getUnstructuredCABundleSources lists objects whose types are not in the client scheme and pulls the base64 encoded caBundle from the given field path
+func getUnstructuredCABundleSources(kind string, apiVersion string, fieldPath []string, dataKey string, listOptions *client.ListOptions, clnt client.Client) ([]CABundleSource, error) {
+	var sources []CABundleSource
+
+	objectList, err := GetUnstructuredList(apiVersion, kind, listOptions, clnt)
+	if err != nil {
+		return sources, err
+	}
+
+	for _, e := range objectList.Items {
+		encodedBundle, found, err := unstructured.NestedString(e.Object, fieldPath...)
+		if err != nil || !found || encodedBundle == "" {
+			continue
+		}
+		// The caBundle is a []byte field and is serialized as base64
+		caBundle, err := base64.StdEncoding.DecodeString(encodedBundle)
+		if err != nil {
+			lggr.Error(err, "Failed to decode caBundle on "+kind+"/"+e.GetName())
+			continue
+		}
+		sources = append(sources, CABundleSource{Name: e.GetName(), DataKey: dataKey, APIVersion: apiVersion, CABundle: caBundle})
+	}
+
+	return sources, nil
+}
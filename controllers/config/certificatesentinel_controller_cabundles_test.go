@@ -0,0 +1,129 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/base64"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newUnstructuredCABundleHolder returns an object of a kind outside of the client scheme with a base64 encoded caBundle set at the field path
+func newUnstructuredCABundleHolder(apiVersion string, kind string, name string, caBundle string, fieldPath ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if caBundle != "" {
+		_ = unstructured.SetNestedField(obj.Object, caBundle, fieldPath...)
+	}
+	return obj
+}
+
+func TestGetCABundleSources(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	apiServicePath := []string{"spec", "caBundle"}
+	conversionPath := []string{"spec", "conversion", "webhook", "clientConfig", "caBundle"}
+
+	// APIServices and CRDs are read as unstructured objects since their types are not in the client scheme
+	scheme := runtime.NewScheme()
+	g := NewWithT(t)
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	for _, gvk := range []schema.GroupVersionKind{{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"}, {Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+
+	clnt := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy-webhooks"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{Name: "pods.policy.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("validating-ca")}},
+				{Name: "no-ca.policy.example.com"},
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "injector", Labels: map[string]string{"team": "mesh"}},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "sidecar.mesh.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("mutating-ca")}}},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaulter"},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "defaults.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("defaulter-ca")}}},
+		},
+		newUnstructuredCABundleHolder("apiregistration.k8s.io/v1", "APIService", "v1beta1.metrics.k8s.io", encode("metrics-ca"), apiServicePath...),
+		newUnstructuredCABundleHolder("apiregistration.k8s.io/v1", "APIService", "v1.apps", "", apiServicePath...),
+		newUnstructuredCABundleHolder("apiregistration.k8s.io/v1", "APIService", "v1.broken.example.com", "not base64!", apiServicePath...),
+		newUnstructuredCABundleHolder("apiextensions.k8s.io/v1", "CustomResourceDefinition", "widgets.example.com", encode("conversion-ca"), conversionPath...),
+		newUnstructuredCABundleHolder("apiextensions.k8s.io/v1", "CustomResourceDefinition", "gadgets.example.com", ""),
+	).Build()
+
+	tests := []struct {
+		name          string
+		kind          string
+		labelSelector labels.Selector
+		want          []CABundleSource
+	}{
+		{
+			name: "validating webhooks with a caBundle",
+			kind: "ValidatingWebhookConfiguration",
+			want: []CABundleSource{{Name: "policy-webhooks", DataKey: "webhooks[pods.policy.example.com].clientConfig.caBundle", APIVersion: "admissionregistration.k8s.io/v1", CABundle: []byte("validating-ca")}},
+		},
+		{
+			name:          "mutating webhooks matching the label selector",
+			kind:          "MutatingWebhookConfiguration",
+			labelSelector: labels.SelectorFromSet(labels.Set{"team": "mesh"}),
+			want:          []CABundleSource{{Name: "injector", DataKey: "webhooks[sidecar.mesh.example.com].clientConfig.caBundle", APIVersion: "admissionregistration.k8s.io/v1", CABundle: []byte("mutating-ca")}},
+		},
+		{
+			name: "APIServices with a decodable caBundle",
+			kind: "APIService",
+			want: []CABundleSource{{Name: "v1beta1.metrics.k8s.io", DataKey: "spec.caBundle", APIVersion: "apiregistration.k8s.io/v1", CABundle: []byte("metrics-ca")}},
+		},
+		{
+			name: "CRD conversion webhooks",
+			kind: "CustomResourceDefinition",
+			want: []CABundleSource{{Name: "widgets.example.com", DataKey: "spec.conversion.webhook.clientConfig.caBundle", APIVersion: "apiextensions.k8s.io/v1", CABundle: []byte("conversion-ca")}},
+		},
+		{
+			name: "kinds without a caBundle",
+			kind: "Secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			labelSelector := tt.labelSelector
+			if labelSelector == nil {
+				labelSelector = labels.Everything()
+			}
+			sources, err := GetCABundleSources(tt.kind, labelSelector, clnt)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sources).To(Equal(tt.want))
+			g.Expect(IsCABundleKind(tt.kind)).To(Equal(tt.want != nil))
+		})
+	}
+}
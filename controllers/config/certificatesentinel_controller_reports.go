@@ -19,12 +19,12 @@ package config
 import (
 	"bytes"
 	"fmt"
+	htmltemplate "html/template"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
 	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
 	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
-	corev1 "k8s.io/api/core/v1"
 
 	"strconv"
 	"strings"
@@ -55,96 +55,173 @@ func intervalToSeconds(effectiveReportInterval string) int64 {
 	return secondsToAdd
 }
 
+// isReportDue checks if a report is due, holding reports that fall in the `quietHours`
+func isReportDue(alertConfig configv1.AlertConfiguration, lastReportSentTime int64, currentUnixTime int64, lggr logr.Logger) bool {
+	// The first report is sent straight away
+	reportDue := (lastReportSentTime == currentUnixTime) || scheduledReportDue(alertConfig, lastReportSentTime, currentUnixTime, lggr)
+
+	if reportDue && alertConfig.QuietHours != "" {
+		quiet, err := helpers.InQuietHours(alertConfig.QuietHours, alertConfig.ReportTimeZone, time.Unix(currentUnixTime, 0))
+		if err != nil {
+			lggr.Error(err, "Invalid quietHours, ignoring them!")
+		} else if quiet {
+			LogWithLevel("Holding report for "+alertConfig.QuietHours+" quiet hours", 2, lggr)
+			return false
+		}
+	}
+
+	return reportDue
+}
+
+// scheduledReportDue checks if the cron `reportSchedule` in the `reportTimeZone` has fired since the last report, or else if the `reportInterval` has passed
+func scheduledReportDue(alertConfig configv1.AlertConfiguration, lastReportSentTime int64, currentUnixTime int64, lggr logr.Logger) bool {
+	if alertConfig.ReportSchedule != "" {
+		nextReport, err := helpers.NextScheduledReport(alertConfig.ReportSchedule, alertConfig.ReportTimeZone, time.Unix(lastReportSentTime, 0))
+		if err == nil {
+			return nextReport.Unix() <= currentUnixTime
+		}
+		lggr.Error(err, "Invalid reportSchedule or reportTimeZone, falling back to the reportInterval!")
+	}
+
+	effectiveReportInterval := defaults.SetDefaultString(defaults.ReportInterval, alertConfig.ReportInterval)
+	// Next expected time to send is before the current time, overdue to send
+	return (lastReportSentTime + intervalToSeconds(effectiveReportInterval)) < currentUnixTime
+}
+
 // processReport processes reports for the CertificateSentinel CRD
-func processReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger, clnt client.Client) int64 {
+// It returns the time the last report was delivered, which only advances when every report was delivered, if a delivery was attempted, and any delivery error
+func processReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger, clnt client.Client) (int64, []configv1.ReportDelivery, bool, error) {
 	// Set up variables
 	currentUnixTime := time.Now().Unix()
 	lastReportSentTime := defaults.SetDefaultInt64(currentUnixTime, certificateSentinel.Status.LastReportSent)
-	effectiveReportInterval := defaults.SetDefaultString(defaults.ReportInterval, certificateSentinel.Spec.Alert.AlertConfiguration.ReportInterval)
 
-	// Add the times together
-	addedTime := (lastReportSentTime + intervalToSeconds(effectiveReportInterval))
+	if isReportDue(certificateSentinel.Spec.Alert.AlertConfiguration, lastReportSentTime, currentUnixTime, lggr) {
+		// List the most urgent certificates first
+		certificateSentinel.Status.DiscoveredCertificates = sortCertificatesByUrgency(certificateSentinel.Status.DiscoveredCertificates)
+
+		// Leave the certificates below the alert's minimum severity out of the report
+		minimumSeverity := certificateSentinel.Spec.Alert.AlertConfiguration.MinimumSeverity
+		if minimumSeverity != "" {
+			certificateSentinel.Status.DiscoveredCertificates = filterCertificatesBySeverity(certificateSentinel.Status.DiscoveredCertificates, minimumSeverity)
+			if !hasCertificatesAtRisk(certificateSentinel.Status.DiscoveredCertificates) {
+				LogWithLevel("No certificates at or above the "+minimumSeverity+" severity, skipping report for "+certificateSentinel.Spec.Alert.AlertName, 2, lggr)
+				// Nothing was sent, so the previous LastReportSent is kept and the report goes out once a certificate reaches the minimum severity
+				return certificateSentinel.Status.LastReportSent, certificateSentinel.Status.ReportDeliveries, false, nil
+			}
+		}
 
-	// Next expected time to send is before the current time, overdue to send
-	if (addedTime < time.Now().Unix()) || (lastReportSentTime == currentUnixTime) {
 		LogWithLevel("Dispatching report for "+certificateSentinel.Spec.Alert.AlertName, 2, lggr)
+		reportTemplates := loadReportTemplates(certificateReportTemplates(), certificateSentinel.Spec.Alert, certificateSentinel.Namespace, lggr, clnt)
 
 		// Send out alert based on alert type
 		switch certificateSentinel.Spec.Alert.AlertType {
 		case "smtp":
-			createSMTPReport(certificateSentinel, lggr, clnt)
+			// Only the routes not yet delivered in this report cycle are sent, so recipients that already have the report are not sent it again
+			routes := routeSMTPReport(certificateSentinel, lggr, clnt)
+			var recipients [][]string
+			for _, route := range routes {
+				recipients = append(recipients, route.To)
+			}
+			deliveries, attempted, err := deliverReportRoutes(recipients, certificateSentinel.Status.ReportDeliveries, certificateSentinel.Spec.Alert.AlertConfiguration, time.Now(), func(i int) error {
+				_, err := createSMTPReport(routes[i].CertificateSentinel, routes[i].To, reportTemplates, lggr, clnt)
+				return err
+			}, lggr)
+			// Keep the previous LastReportSent until every route is delivered
+			if deliveries != nil {
+				return certificateSentinel.Status.LastReportSent, deliveries, attempted, err
+			}
 		case "logger":
-			loggr := createLoggerReport(certificateSentinel, lggr)
+			loggr := createLoggerReport(certificateSentinel, reportTemplates, lggr)
 			lggr.Info(loggr)
 		default:
-			loggr := createLoggerReport(certificateSentinel, lggr)
+			loggr := createLoggerReport(certificateSentinel, reportTemplates, lggr)
 			lggr.Info(loggr)
 		}
 
-		return time.Now().Unix()
+		return time.Now().Unix(), nil, true, nil
 	}
-	return lastReportSentTime
+	// Keep the previous LastReportSent, which is unset when the first report is held for quiet hours
+	return certificateSentinel.Status.LastReportSent, certificateSentinel.Status.ReportDeliveries, false, nil
 }
 
 // createLoggerReport loops through CertificateSentinel.Status and creates a stdout report
-func createLoggerReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger) string {
-	return createTextTableReport(certificateSentinel, lggr)
+func createLoggerReport(certificateSentinel configv1.CertificateSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
+	return createTextTableReport(certificateSentinel, reportTemplates, lggr)
 }
 
-// createSMTPReport loops through CertificateSentinel.Status and sends an email report
-func createSMTPReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger, clnt client.Client) string {
+// createSMTPReport loops through CertificateSentinel.Status and sends an email report to the provided recipients
+func createSMTPReport(certificateSentinel configv1.CertificateSentinel, to []string, reportTemplates ReportTemplates, lggr logr.Logger, clnt client.Client) (string, error) {
 
-	textEmailReport := tableTextReportToBasicHTMLReport(createTextTableReport(certificateSentinel, lggr))
-	htmlEmailReport := createSMTPHTMLReport(certificateSentinel, lggr)
+	textEmailReport := createTextTableReport(certificateSentinel, reportTemplates, lggr)
+	htmlEmailReport := createSMTPHTMLReport(certificateSentinel, reportTemplates, lggr)
+	attachments := createReportAttachments(certificateSentinel.Spec.Alert.AlertConfiguration.SMTPAttachments, "certificates-at-risk", certificateReportRows(certificateSentinel.Status.DiscoveredCertificates), atRiskCertificates(certificateSentinel.Status.DiscoveredCertificates), lggr)
+	currentConfig, _ := config.GetConfig()
+	var triggeredDaysOut [][]int
+	severity := ""
+	atRisk := atRiskCertificates(certificateSentinel.Status.DiscoveredCertificates)
+	expiringCerts := 0
+	revokedCerts := 0
+	policyViolations := 0
+	for _, certInfo := range atRisk {
+		if certInfo.Revoked {
+			revokedCerts++
+			severity = helpers.SeverityRevoked
+		}
+		if len(certInfo.TriggeredDaysOut) > 0 {
+			expiringCerts++
+			triggeredDaysOut = append(triggeredDaysOut, certInfo.TriggeredDaysOut)
+			if helpers.SeverityRank(certInfo.Severity) > helpers.SeverityRank(severity) {
+				severity = certInfo.Severity
+			}
+		}
+		for _, violation := range certInfo.PolicyViolations {
+			policyViolations++
+			if helpers.SeverityRank(violation.Severity) > helpers.SeverityRank(severity) {
+				severity = violation.Severity
+			}
+		}
+	}
+	subject := renderReportSubject(reportTemplates.Subject, ReportSubjectStructure{
+		Namespace:          certificateSentinel.Namespace,
+		Name:               certificateSentinel.Name,
+		AlertName:          certificateSentinel.Spec.Alert.AlertName,
+		ClusterName:        reportClusterName(certificateSentinel.Spec.Alert.AlertConfiguration, currentConfig.Host),
+		ClusterAPIEndpoint: currentConfig.Host + currentConfig.APIPath,
+		TotalCerts:         strconv.Itoa(len(certificateSentinel.Status.DiscoveredCertificates)),
+		ExpiringCerts:      strconv.Itoa(expiringCerts),
+		RevokedCerts:       strconv.Itoa(revokedCerts),
+		PolicyViolations:   strconv.Itoa(policyViolations),
+		MostUrgentDaysOut:  mostUrgentDaysOut(triggeredDaysOut),
+		Severity:           severity,
+	}, lggr)
 
 	// Loop through the alerts
 	alert := certificateSentinel.Spec.Alert
 	if alert.AlertType == "smtp" {
-		// Set up basic SMTP vars
-		var username string
-		var password string
-		var identity string
-		var cramSecret string
-
-		// Set defaults
-		useTLS := defaults.SetDefaultBool(&defaults.SMTPAuthUseSSL, alert.AlertConfiguration.SMTPAuthUseSSL)
-		useSTARTTLS := defaults.SetDefaultBool(&defaults.SMTPAuthUseSTARTTLS, alert.AlertConfiguration.SMTPAuthUseSTARTTLS)
-
-		// Get SMTP Authentication Secret if the AuthType is not `none`
-		smtpAuthSecret := &corev1.Secret{}
-		if alert.AlertConfiguration.SMTPAuthType != "none" {
-			smtpAuthSecret, _ = GetSecret(alert.AlertConfiguration.SMTPAuthSecretName, certificateSentinel.Namespace, clnt)
+		// Set up the SMTP server connection, authentication, and TLS options
+		serverConfig, err := newSMTPServerConfig(alert.AlertConfiguration, certificateSentinel.Namespace, clnt)
+		if err != nil {
+			return textEmailReport, err
 		}
 
-		// Assign SMTP Auth vars where needed
-		if len(smtpAuthSecret.Data) > 0 {
-			username = string(smtpAuthSecret.Data["username"])
-			password = string(smtpAuthSecret.Data["password"])
-			identity = string(smtpAuthSecret.Data["identity"])
-			cramSecret = string(smtpAuthSecret.Data["cram"])
-		}
-
-		// Send the message
-		helpers.SendSMTPMail(alert.AlertConfiguration.SMTPAuthType,
-			username,
-			password,
-			identity,
-			cramSecret,
-			useTLS,
-			useSTARTTLS,
-			alert.AlertConfiguration.SMTPDestinationEmailAddresses,
+		// Send the message once, failed deliveries are retried with backoff on a later scan
+		err = helpers.SendSMTPMail(serverConfig,
+			to,
 			alert.AlertConfiguration.SMTPSenderEmailAddress,
-			alert.AlertConfiguration.SMTPEndpoint,
-			"Certificate Sentinel Operator - CertificateSentinel Report",
+			subject,
 			textEmailReport,
-			htmlEmailReport)
+			htmlEmailReport,
+			attachments)
+		if err != nil {
+			return textEmailReport, err
+		}
 	}
 
-	return textEmailReport
+	return textEmailReport, nil
 }
 
 // createTextTableReport creates a Text-based table of the report, used in logger reports and text-based SMTP reports
-func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger) string {
+func createTextTableReport(certificateSentinel configv1.CertificateSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
 	currentConfig, _ := config.GetConfig()
 	clusterEndpoint := currentConfig.Host
 	apiPath := currentConfig.APIPath
@@ -162,23 +239,27 @@ func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lgg
 	CACNLongest := "Signing CA CN"
 	ExpirationDateLongest := "Expiration Date"
 	TriggeredDaysOutLongest := "Triggered Days Out"
+	PolicyViolationsLongest := "Policy Violations"
 
 	// Loop through the .status.DiscoveredCertificates
 	for _, certInfo := range certificateSentinel.Status.DiscoveredCertificates {
 		// If this is an expired certificate
-		if len(certInfo.TriggeredDaysOut) > 0 {
-			expiredCertificateCount++
+		if isCertificateAtRisk(certInfo) {
+			if len(certInfo.TriggeredDaysOut) > 0 {
+				expiredCertificateCount++
+			}
 			// Set up Logger Lines for length
 			APIVersionLongest = helpers.ReturnLonger(APIVersionLongest, certInfo.APIVersion)
 			KindLongest = helpers.ReturnLonger(KindLongest, certInfo.Kind)
 			NamespaceLongest = helpers.ReturnLonger(NamespaceLongest, certInfo.Namespace)
 			NameLongest = helpers.ReturnLonger(NameLongest, certInfo.Name)
-			DataKeyLongest = helpers.ReturnLonger(DataKeyLongest, certInfo.DataKey)
+			DataKeyLongest = helpers.ReturnLonger(DataKeyLongest, reportDataKey(certInfo))
 			CertCNLongest = helpers.ReturnLonger(CertCNLongest, certInfo.CommonName)
 			IsCALongest = helpers.ReturnLonger(IsCALongest, strconv.FormatBool(certInfo.IsCertificateAuthority))
 			CACNLongest = helpers.ReturnLonger(CACNLongest, certInfo.CertificateAuthorityCommonName)
-			ExpirationDateLongest = helpers.ReturnLonger(ExpirationDateLongest, certInfo.Expiration)
+			ExpirationDateLongest = helpers.ReturnLonger(ExpirationDateLongest, reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z))
 			TriggeredDaysOutLongest = helpers.ReturnLonger(TriggeredDaysOutLongest, strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"))
+			PolicyViolationsLongest = helpers.ReturnLonger(PolicyViolationsLongest, joinPolicyViolationRules(certInfo.PolicyViolations))
 		}
 	}
 
@@ -193,27 +274,32 @@ func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lgg
 	CACNLength := len(CACNLongest)
 	ExpirationDateLength := len(ExpirationDateLongest)
 	TriggeredDaysOutLength := len(TriggeredDaysOutLongest)
-	TotalLineLength := (APIVersionLength + KindLength + NamespaceLength + NameLength + DataKeyLength + CertCNLength + IsCALength + CACNLength + ExpirationDateLength + TriggeredDaysOutLength + 31)
+	PolicyViolationsLength := len(PolicyViolationsLongest)
+	TotalLineLength := (APIVersionLength + KindLength + NamespaceLength + NameLength + DataKeyLength + CertCNLength + IsCALength + CACNLength + ExpirationDateLength + TriggeredDaysOutLength + PolicyViolationsLength + 34)
 	LineBreak := helpers.StrPad("-", TotalLineLength, "-", "BOTH")
 
 	// Loop through the .status.DiscoveredCertificates
 	for _, certInfo := range certificateSentinel.Status.DiscoveredCertificates {
-		if len(certInfo.TriggeredDaysOut) > 0 {
+		if isCertificateAtRisk(certInfo) {
 			// Set up Logger Lines
 			loggerReportLineStructure := LoggerReportLineStructure{
 				APIVersion:                     helpers.StrPad(certInfo.APIVersion, APIVersionLength, " ", "BOTH"),
 				Kind:                           helpers.StrPad(certInfo.Kind, KindLength, " ", "BOTH"),
 				Namespace:                      helpers.StrPad(certInfo.Namespace, NamespaceLength, " ", "BOTH"),
 				Name:                           helpers.StrPad(certInfo.Name, NameLength, " ", "BOTH"),
-				Key:                            helpers.StrPad(certInfo.DataKey, DataKeyLength, " ", "BOTH"),
+				Key:                            helpers.StrPad(reportDataKey(certInfo), DataKeyLength, " ", "BOTH"),
 				CommonName:                     helpers.StrPad(certInfo.CommonName, CertCNLength, " ", "BOTH"),
 				IsCA:                           helpers.StrPad(strconv.FormatBool(certInfo.IsCertificateAuthority), IsCALength, " ", "BOTH"),
 				CertificateAuthorityCommonName: helpers.StrPad(certInfo.CertificateAuthorityCommonName, CACNLength, " ", "BOTH"),
-				ExpirationDate:                 helpers.StrPad(certInfo.Expiration, ExpirationDateLength, " ", "BOTH"),
+				ExpirationDate:                 helpers.StrPad(reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z), ExpirationDateLength, " ", "BOTH"),
+				NotBefore:                      certInfo.NotBefore.Time,
+				NotAfter:                       certInfo.NotAfter.Time,
+				Details:                        certInfo.Details,
 				TriggeredDaysOut:               helpers.StrPad(strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"), TriggeredDaysOutLength, " ", "BOTH"),
+				PolicyViolations:               helpers.StrPad(joinPolicyViolationRules(certInfo.PolicyViolations), PolicyViolationsLength, " ", "BOTH"),
 			}
 			lineBuf := new(bytes.Buffer)
-			loggerLineTemplate, err := template.New("loggerLine").Parse(LoggerReportLine)
+			loggerLineTemplate, err := template.New("loggerLine").Parse(reportTemplates.TextRow)
 			if err != nil {
 				lggr.Error(err, "Error parsing loggerLineTemplate template!")
 			}
@@ -238,9 +324,10 @@ func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lgg
 		CertificateAuthorityCommonName: helpers.StrPad("Signing CA CN", CACNLength, " ", "BOTH"),
 		ExpirationDate:                 helpers.StrPad("Expiration Date", ExpirationDateLength, " ", "BOTH"),
 		TriggeredDaysOut:               helpers.StrPad("Triggered Days Out", TriggeredDaysOutLength, " ", "BOTH"),
+		PolicyViolations:               helpers.StrPad("Policy Violations", PolicyViolationsLength, " ", "BOTH"),
 	}
 	headerBuf := new(bytes.Buffer)
-	loggerHeaderTemplate, err := template.New("loggerHeader").Parse(LoggerReportHeader)
+	loggerHeaderTemplate, err := template.New("loggerHeader").Parse(reportTemplates.TextHeader)
 	if err != nil {
 		lggr.Error(err, "Error parsing loggerHeaderTemplate template!", 1, lggr)
 	}
@@ -257,13 +344,15 @@ func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lgg
 		ClusterAPIEndpoint: clusterEndpoint + apiPath,
 		TotalCerts:         strconv.Itoa(len(certificateSentinel.Status.DiscoveredCertificates)),
 		ExpiringCerts:      strconv.Itoa(expiredCertificateCount),
+		RevokedCerts:       strconv.Itoa(len(revokedCertificates(certificateSentinel.Status.DiscoveredCertificates))),
+		RevokedSection:     createRevokedTextSection(certificateSentinel.Status.DiscoveredCertificates),
 		ReportLines:        reportLines,
 		Footer:             headerBuf.String(),
 		Header:             headerBuf.String(),
 		Divider:            LineBreak,
 	}
 	reportBuf := new(bytes.Buffer)
-	loggerReportTemplate, err := template.New("loggerReport").Parse(LoggerReport)
+	loggerReportTemplate, err := template.New("loggerReport").Parse(reportTemplates.Text)
 	if err != nil {
 		lggr.Error(err, "Error parsing loggerReportTemplate template!")
 	}
@@ -276,7 +365,7 @@ func createTextTableReport(certificateSentinel configv1.CertificateSentinel, lgg
 }
 
 // createSMTPHTMLReport creates a rich HTML-based table of the report, used in SMTP reports
-func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger) string {
+func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
 
 	// Set up init vars
 	var reportLines string
@@ -288,8 +377,10 @@ func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr
 
 	// Loop through the .status.DiscoveredCertificates
 	for iCI, certInfo := range certificateSentinel.Status.DiscoveredCertificates {
-		if len(certInfo.TriggeredDaysOut) > 0 {
-			expiredCertificateCount++
+		if isCertificateAtRisk(certInfo) {
+			if len(certInfo.TriggeredDaysOut) > 0 {
+				expiredCertificateCount++
+			}
 			// Set up styles
 			var rowStyles string
 			cellStyles := "padding:6px;text-align:left;"
@@ -299,30 +390,27 @@ func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr
 				rowStyles = "background:#FAFAFA;"
 			}
 
-			// Parse time to format
-			inputTimelayout := "2006-01-02 15:04:05 -0700 MST"
-			t, err := time.Parse(inputTimelayout, certInfo.Expiration)
-			if err != nil {
-				fmt.Printf("Error parsing time! %+v\n", err)
-			}
-
 			// Set up HTML Lines
 			htmlSMTPReportLine := HTMLReportLineStructure{
 				APIVersion:                     certInfo.APIVersion,
 				Kind:                           certInfo.Kind,
 				Namespace:                      certInfo.Namespace,
 				Name:                           certInfo.Name,
-				Key:                            certInfo.DataKey,
+				Key:                            reportDataKey(certInfo),
 				CommonName:                     certInfo.CommonName,
 				IsCA:                           strconv.FormatBool(certInfo.IsCertificateAuthority),
 				CertificateAuthorityCommonName: certInfo.CertificateAuthorityCommonName,
-				ExpirationDate:                 string(t.Format(time.RFC822Z)),
+				ExpirationDate:                 reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z),
+				NotBefore:                      certInfo.NotBefore.Time,
+				NotAfter:                       certInfo.NotAfter.Time,
+				Details:                        certInfo.Details,
 				TriggeredDaysOut:               strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"),
-				RowStyles:                      rowStyles,
-				CellStyles:                     cellStyles,
+				PolicyViolations:               joinPolicyViolationRules(certInfo.PolicyViolations),
+				RowStyles:                      htmltemplate.CSS(rowStyles),
+				CellStyles:                     htmltemplate.CSS(cellStyles),
 			}
 			lineBuf := new(bytes.Buffer)
-			htmlLineTemplate, err := template.New("tableLine").Parse(HTMLSMTPReportLine)
+			htmlLineTemplate, err := htmltemplate.New("tableLine").Parse(reportTemplates.HTMLRow)
 			if err != nil {
 				lggr.Error(err, "Error parsing htmlSMTPReportLine template!")
 			}
@@ -351,11 +439,12 @@ func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr
 		CertificateAuthorityCommonName: "Signing CA CN",
 		ExpirationDate:                 "Expiration Date",
 		TriggeredDaysOut:               "Triggered Days Out",
-		RowStyles:                      rowStyles,
-		CellStyles:                     cellStyles,
+		PolicyViolations:               "Policy Violations",
+		RowStyles:                      htmltemplate.CSS(rowStyles),
+		CellStyles:                     htmltemplate.CSS(cellStyles),
 	}
 	headerBuf := new(bytes.Buffer)
-	tableHeaderTemplate, err := template.New("tableHeader").Parse(HTMLSMTPReportHeader)
+	tableHeaderTemplate, err := htmltemplate.New("tableHeader").Parse(reportTemplates.HTMLHeader)
 	if err != nil {
 		lggr.Error(err, "Error parsing tableHeaderTemplate template!")
 	}
@@ -372,13 +461,15 @@ func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr
 		ClusterAPIEndpoint: clusterEndpoint + apiPath,
 		TotalCerts:         strconv.Itoa(len(certificateSentinel.Status.DiscoveredCertificates)),
 		ExpiringCerts:      strconv.Itoa(expiredCertificateCount),
-		TableRows:          reportLines,
-		THead:              headerBuf.String(),
-		TFoot:              headerBuf.String(),
-		BodyDivider:        HTMLSMTPReportBodyDivider,
+		RevokedCerts:       strconv.Itoa(len(revokedCertificates(certificateSentinel.Status.DiscoveredCertificates))),
+		RevokedSection:     htmltemplate.HTML(createRevokedHTMLSection(certificateSentinel.Status.DiscoveredCertificates)),
+		TableRows:          htmltemplate.HTML(reportLines),
+		THead:              htmltemplate.HTML(headerBuf.String()),
+		TFoot:              htmltemplate.HTML(headerBuf.String()),
+		BodyDivider:        htmltemplate.HTML(HTMLSMTPReportBodyDivider),
 	}
 	reportBuf := new(bytes.Buffer)
-	htmlReportTemplate, err := template.New("HTMLReport").Parse(HTMLSMTPReportBody)
+	htmlReportTemplate, err := htmltemplate.New("HTMLReport").Parse(reportTemplates.HTML)
 	if err != nil {
 		lggr.Error(err, "Error parsing htmlReportTemplate template!")
 	}
@@ -390,21 +481,76 @@ func createSMTPHTMLReport(certificateSentinel configv1.CertificateSentinel, lggr
 	return reportBuf.String()
 }
 
-// tableTextReportToBasicHTMLReport takes a basic text-based table report used by logger and turns it into a basic HTML report
-func tableTextReportToBasicHTMLReport(table string) string {
-	// Set up Logger Report
-	textSMTPReportStructure := TextSMTPReportStructure{
-		Content: table,
+// revokedCertificates returns only the certificates reported as revoked
+func revokedCertificates(certificates []configv1.CertificateInformation) []configv1.CertificateInformation {
+	revoked := []configv1.CertificateInformation{}
+	for _, certInfo := range certificates {
+		if certInfo.Revoked {
+			revoked = append(revoked, certInfo)
+		}
 	}
-	htmlBuf := new(bytes.Buffer)
-	textSMTPReportTemplate, err := template.New("textSMTPReport").Parse(TextSMTPReportDocument)
-	if err != nil {
-		lggr.Error(err, "Error parsing textSMTPReportTemplate template!")
+	return revoked
+}
+
+// revokedCertificateLine describes where a revoked certificate was found and when and why it was revoked
+func revokedCertificateLine(certInfo configv1.CertificateInformation) string {
+	line := certInfo.Kind + " " + certInfo.Namespace + "/" + certInfo.Name + " " + reportDataKey(certInfo) + " - " + certInfo.CommonName + " revoked"
+	if certInfo.Revocation == nil {
+		return line
 	}
-	err = textSMTPReportTemplate.Execute(htmlBuf, textSMTPReportStructure)
-	if err != nil {
-		lggr.Error(err, "Error executing textSMTPReportTemplate template!")
+	if certInfo.Revocation.RevokedAt != nil {
+		line += " " + certInfo.Revocation.RevokedAt.UTC().Format(time.RFC822Z)
+	}
+	if certInfo.Revocation.Reason != "" {
+		line += " (" + certInfo.Revocation.Reason + ")"
 	}
+	if certInfo.Revocation.Source != "" {
+		line += " via " + certInfo.Revocation.Source
+	}
+	return line
+}
+
+// createRevokedTextSection lists the revoked certificates for the text report, empty when there are none
+func createRevokedTextSection(certificates []configv1.CertificateInformation) string {
+	revoked := revokedCertificates(certificates)
+	if len(revoked) == 0 {
+		return ""
+	}
+	section := "Revoked Certificates:\n"
+	for _, certInfo := range revoked {
+		section += "  - " + revokedCertificateLine(certInfo) + "\n"
+	}
+	return section
+}
 
-	return htmlBuf.String()
+// createRevokedHTMLSection lists the revoked certificates for the HTML report, empty when there are none
+func createRevokedHTMLSection(certificates []configv1.CertificateInformation) string {
+	revoked := revokedCertificates(certificates)
+	if len(revoked) == 0 {
+		return ""
+	}
+	section := `<div style="text-align:left;"><h3 style="color:#C00;">Revoked Certificates</h3><ul>`
+	for _, certInfo := range revoked {
+		section += "<li>" + template.HTMLEscapeString(revokedCertificateLine(certInfo)) + "</li>"
+	}
+	return section + "</ul></div>"
+}
+
+// reportDataKey returns the DataKey to display in reports, affixing the kubeconfig context, cluster, and user names for certificates embedded in a kubeconfig file
+func reportDataKey(certInfo configv1.CertificateInformation) string {
+	if certInfo.Kubeconfig == nil {
+		return certInfo.DataKey
+	}
+
+	var kubeconfigNames []string
+	if certInfo.Kubeconfig.Context != "" {
+		kubeconfigNames = append(kubeconfigNames, "context: "+certInfo.Kubeconfig.Context)
+	}
+	if certInfo.Kubeconfig.Cluster != "" {
+		kubeconfigNames = append(kubeconfigNames, "cluster: "+certInfo.Kubeconfig.Cluster)
+	}
+	if certInfo.Kubeconfig.User != "" {
+		kubeconfigNames = append(kubeconfigNames, "user: "+certInfo.Kubeconfig.User)
+	}
+	return certInfo.DataKey + " (" + strings.Join(kubeconfigNames, ", ") + ")"
 }
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIsReportDue(t *testing.T) {
+	// Friday the 1st of October 2021, 09:30 UTC
+	now := time.Date(2021, time.October, 1, 9, 30, 0, 0, time.UTC)
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2021, time.October, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name           string
+		alertConfig    configv1.AlertConfiguration
+		lastReportSent time.Time
+		now            time.Time
+		want           bool
+	}{
+		{
+			name:           "first report",
+			lastReportSent: now,
+			now:            now,
+			want:           true,
+		},
+		{
+			name:           "daily interval not passed",
+			lastReportSent: now.Add(-23 * time.Hour),
+			now:            now,
+			want:           false,
+		},
+		{
+			name:           "daily interval passed",
+			lastReportSent: now.Add(-25 * time.Hour),
+			now:            now,
+			want:           true,
+		},
+		{
+			name:           "weekly interval not passed",
+			alertConfig:    configv1.AlertConfiguration{ReportInterval: "weekly"},
+			lastReportSent: now.AddDate(0, 0, -6),
+			now:            now,
+			want:           false,
+		},
+		{
+			name:           "cron schedule fired",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * *"},
+			lastReportSent: at(8, 0),
+			now:            at(9, 0),
+			want:           true,
+		},
+		{
+			name:           "cron schedule not fired yet",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * *"},
+			lastReportSent: at(8, 0),
+			now:            at(8, 59),
+			want:           false,
+		},
+		{
+			name:           "cron schedule skips the weekend",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * 1-5"},
+			lastReportSent: at(9, 0),
+			now:            at(9, 0).AddDate(0, 0, 2),
+			want:           false,
+		},
+		{
+			name:           "cron schedule in a time zone has not fired",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * *", ReportTimeZone: "America/New_York"},
+			lastReportSent: at(8, 0),
+			now:            at(9, 30),
+			want:           false,
+		},
+		{
+			name:           "cron schedule in a time zone fired",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * *", ReportTimeZone: "America/New_York"},
+			lastReportSent: at(8, 0),
+			now:            at(13, 0),
+			want:           true,
+		},
+		{
+			name:           "invalid cron schedule falls back to the interval",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "every morning"},
+			lastReportSent: now.Add(-25 * time.Hour),
+			now:            now,
+			want:           true,
+		},
+		{
+			name:           "invalid time zone falls back to the interval",
+			alertConfig:    configv1.AlertConfiguration{ReportSchedule: "0 9 * * *", ReportTimeZone: "Mars/Olympus_Mons"},
+			lastReportSent: at(8, 0),
+			now:            at(9, 30),
+			want:           false,
+		},
+		{
+			name:           "quiet hours before midnight",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "22:00-06:00"},
+			lastReportSent: at(23, 0).AddDate(0, 0, -2),
+			now:            at(23, 0),
+			want:           false,
+		},
+		{
+			name:           "quiet hours wrapped past midnight",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "22:00-06:00"},
+			lastReportSent: at(2, 0).AddDate(0, 0, -2),
+			now:            at(2, 0),
+			want:           false,
+		},
+		{
+			name:           "after quiet hours",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "22:00-06:00"},
+			lastReportSent: at(6, 0).AddDate(0, 0, -2),
+			now:            at(6, 0),
+			want:           true,
+		},
+		{
+			name:           "quiet hours in the report time zone",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "22:00-06:00", ReportTimeZone: "America/New_York"},
+			lastReportSent: at(8, 0).AddDate(0, 0, -2),
+			now:            at(8, 0),
+			want:           false,
+		},
+		{
+			name:           "first report held for quiet hours",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "22:00-06:00"},
+			lastReportSent: at(2, 0),
+			now:            at(2, 0),
+			want:           false,
+		},
+		{
+			name:           "invalid quiet hours are ignored",
+			alertConfig:    configv1.AlertConfiguration{QuietHours: "after 10pm"},
+			lastReportSent: now,
+			now:            now,
+			want:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(isReportDue(tt.alertConfig, tt.lastReportSent.Unix(), tt.now.Unix(), lggr)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestProcessReportHoldsFirstReportForQuietHours(t *testing.T) {
+	g := NewWithT(t)
+
+	// Quiet hours around the current time, wrapping past midnight when it is late in the day
+	now := time.Now().UTC()
+	quietHours := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	certificateSentinel := configv1.CertificateSentinel{}
+	certificateSentinel.Spec.Alert = configv1.Alert{AlertName: "ops-team", AlertType: "logger", AlertConfiguration: configv1.AlertConfiguration{QuietHours: quietHours}}
+	certificateSentinel.Status.DiscoveredCertificates = []configv1.CertificateInformation{{Name: "expiring-tls", TriggeredDaysOut: []int{30}}}
+
+	lastReportSent, deliveries, attempted, err := processReport(certificateSentinel, lggr, fake.NewClientBuilder().Build())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempted).To(BeFalse())
+	g.Expect(deliveries).To(BeNil())
+	// The unset LastReportSent keeps the first report due once the quiet hours end
+	g.Expect(lastReportSent).To(BeZero())
+}
+
+func TestProcessReportSkippedBelowMinimumSeverityIsNotSent(t *testing.T) {
+	g := NewWithT(t)
+	lastSent := time.Now().Add(-48 * time.Hour).Unix()
+	certificateSentinel := configv1.CertificateSentinel{}
+	certificateSentinel.Spec.Alert = configv1.Alert{AlertName: "ops-team", AlertType: "logger", AlertConfiguration: configv1.AlertConfiguration{MinimumSeverity: "critical"}}
+	certificateSentinel.Status.LastReportSent = lastSent
+	certificateSentinel.Status.DiscoveredCertificates = []configv1.CertificateInformation{{Name: "expiring-tls", TriggeredDaysOut: []int{60}, Severity: "warning"}}
+
+	lastReportSent, deliveries, attempted, err := processReport(certificateSentinel, lggr, fake.NewClientBuilder().Build())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempted).To(BeFalse())
+	g.Expect(deliveries).To(BeNil())
+	g.Expect(lastReportSent).To(Equal(lastSent))
+}
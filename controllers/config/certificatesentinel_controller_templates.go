@@ -16,6 +16,13 @@ limitations under the License.
 
 package config
 
+import (
+	"html/template"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
 //==================================================================================================
 // Logger Reports - Plain text based for SMTP too
 //==================================================================================================
@@ -26,8 +33,9 @@ CertificateSentinel Report: {{ .Namespace }}/{{ .Name }} ({{ .DateSent }})
   Cluster: {{ .ClusterAPIEndpoint }}
   Total Certificates Found: {{ .TotalCerts }}
   Expiring Certificates Found: {{ .ExpiringCerts }}
+  Revoked Certificates Found: {{ .RevokedCerts }}
 {{ .Divider }}
-
+{{ .RevokedSection }}
 {{ .Divider }}
 {{ .Header }}
 {{ .Divider }}
@@ -36,10 +44,10 @@ CertificateSentinel Report: {{ .Namespace }}/{{ .Name }} ({{ .DateSent }})
 {{ .Divider }}
 `
 
-const LoggerReportLine = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} |
+const LoggerReportLine = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} | {{ .PolicyViolations }} |
 `
 
-const LoggerReportHeader = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} |`
+const LoggerReportHeader = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} | {{ .PolicyViolations }} |`
 
 // loggerReportStructure provides the overall structure to the loggerReport template
 type LoggerReportStructure struct {
@@ -49,6 +57,8 @@ type LoggerReportStructure struct {
 	ClusterAPIEndpoint string
 	TotalCerts         string
 	ExpiringCerts      string
+	RevokedCerts       string
+	RevokedSection     string
 	ReportLines        string
 	Header             string
 	Footer             string
@@ -67,6 +77,7 @@ type LoggerReportHeaderStructure struct {
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
 	TriggeredDaysOut               string
+	PolicyViolations               string
 }
 
 // loggerReportLineStructure provides the struct for the loggerReportLine template
@@ -80,22 +91,17 @@ type LoggerReportLineStructure struct {
 	IsCA                           string
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
+	NotBefore                      time.Time
+	NotAfter                       time.Time
+	Details                        *configv1.CertificateDetails
 	TriggeredDaysOut               string
+	PolicyViolations               string
 }
 
 //==================================================================================================
 // SMTP HTML Reports
 //==================================================================================================
 
-const TextSMTPReportDocument = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
-<html>
-<head></head>
-<body style="font-family:monospace">
-<pre>{{ .Content }}</pre>
-</body>
-</html>
-`
-
 const HTMLSMTPReportBody = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
 <html><head></head><body><div width="100%" style="margin:0!important;padding:10px 0!important;background-color:#ffffff">
 <center style="width:100%;background-color:#ffffff">
@@ -113,10 +119,11 @@ const HTMLSMTPReportBody = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transit
 <tr><td style="text-align:left;padding:6px;"><strong>CertificateSentinel Name:</strong></td><td>{{ .Name }}</td></tr>
 <tr style="background:#EEE;"><td style="text-align:left;padding:6px;"><strong>Total Certificates Found:</strong></td><td>{{ .TotalCerts }}</td></tr>
 <tr><td style="text-align:left;padding:6px;"><strong>Expiring Certificates Found:</strong></td><td>{{ .ExpiringCerts }}</td></tr>
+<tr style="background:#EEE;"><td style="text-align:left;padding:6px;"><strong>Revoked Certificates Found:</strong></td><td>{{ .RevokedCerts }}</td></tr>
 </tbody>
 </table>
 </div>
-<table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%" style="margin:auto">
+{{ .RevokedSection }}<table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%" style="margin:auto">
 <thead style="font-weight:bold;">{{ .THead }}</thead>
 <tbody>
 {{ .TableRows }}
@@ -132,14 +139,9 @@ const HTMLSMTPReportBody = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transit
 const HTMLSMTPReportBodyDivider = `<div style="width:100%;"><hr /></div>`
 const HTMLSMTPReportBodyTableDivider = `<tr><td style="text-align:left">&nbsp;</td></tr>`
 
-const HTMLSMTPReportLine = `<tr style="{{ .RowStyles }}"><td style="{{ .CellStyles }}">{{ .APIVersion }}</td><td style="{{ .CellStyles }}">{{ .Kind }}</td><td style="{{ .CellStyles }}">{{ .Namespace }}</td><td style="{{ .CellStyles }}">{{ .Name }}</td><td style="{{ .CellStyles }}">{{ .Key }}</td><td style="{{ .CellStyles }}">{{ .CommonName }}</td><td style="{{ .CellStyles }}">{{ .IsCA }}</td><td style="{{ .CellStyles }}">{{ .CertificateAuthorityCommonName }}</td><td style="{{ .CellStyles }}">{{ .ExpirationDate }}</td><td style="{{ .CellStyles }}">{{ .TriggeredDaysOut }}</td></tr>`
+const HTMLSMTPReportLine = `<tr style="{{ .RowStyles }}"><td style="{{ .CellStyles }}">{{ .APIVersion }}</td><td style="{{ .CellStyles }}">{{ .Kind }}</td><td style="{{ .CellStyles }}">{{ .Namespace }}</td><td style="{{ .CellStyles }}">{{ .Name }}</td><td style="{{ .CellStyles }}">{{ .Key }}</td><td style="{{ .CellStyles }}">{{ .CommonName }}</td><td style="{{ .CellStyles }}">{{ .IsCA }}</td><td style="{{ .CellStyles }}">{{ .CertificateAuthorityCommonName }}</td><td style="{{ .CellStyles }}">{{ .ExpirationDate }}</td><td style="{{ .CellStyles }}">{{ .TriggeredDaysOut }}</td><td style="{{ .CellStyles }}">{{ .PolicyViolations }}</td></tr>`
 
-const HTMLSMTPReportHeader = `<tr style="background:#EEE;"><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .APIVersion }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Kind }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Namespace }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Name }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Key }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .CommonName }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .IsCA }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .CertificateAuthorityCommonName }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .ExpirationDate }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .TriggeredDaysOut }}</td></tr>`
-
-// TextSMTPReportStructure is just a wrapper for the text-report in an HTML document
-type TextSMTPReportStructure struct {
-	Content string
-}
+const HTMLSMTPReportHeader = `<tr style="background:#EEE;"><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .APIVersion }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Kind }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Namespace }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Name }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .Key }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .CommonName }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .IsCA }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .CertificateAuthorityCommonName }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .ExpirationDate }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .TriggeredDaysOut }}</td><td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .PolicyViolations }}</td></tr>`
 
 // HTMLReportStructure provides the overall structure to the HTMLSMTPReport template
 type HTMLReportStructure struct {
@@ -149,10 +151,12 @@ type HTMLReportStructure struct {
 	ClusterAPIEndpoint string
 	TotalCerts         string
 	ExpiringCerts      string
-	TableRows          string
-	THead              string
-	TFoot              string
-	BodyDivider        string
+	RevokedCerts       string
+	RevokedSection     template.HTML
+	TableRows          template.HTML
+	THead              template.HTML
+	TFoot              template.HTML
+	BodyDivider        template.HTML
 }
 
 // HTMLReportLineStructure provides the struct for the htmlReportLine template
@@ -166,9 +170,13 @@ type HTMLReportLineStructure struct {
 	IsCA                           string
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
+	NotBefore                      time.Time
+	NotAfter                       time.Time
+	Details                        *configv1.CertificateDetails
 	TriggeredDaysOut               string
-	RowStyles                      string
-	CellStyles                     string
+	PolicyViolations               string
+	RowStyles                      template.CSS
+	CellStyles                     template.CSS
 }
 
 // HTMLReportHeaderStructure provides the struct for the htmlReportHeader template
@@ -183,8 +191,9 @@ type HTMLReportHeaderStructure struct {
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
 	TriggeredDaysOut               string
-	RowStyles                      string
-	CellStyles                     string
+	PolicyViolations               string
+	RowStyles                      template.CSS
+	CellStyles                     template.CSS
 }
 
 //==================================================================================================
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"testing"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCertificateChecksumSeedIncludesKubeconfigReference(t *testing.T) {
+	g := NewWithT(t)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "kube-ca"}, Issuer: pkix.Name{CommonName: "kube-ca"}}
+	source := configv1.CertificateInformation{Kind: "Secret", Namespace: "ops", Name: "kubeconfigs", DataKey: "kubeconfig"}
+
+	// The same cluster CA shared by two contexts of one kubeconfig is tracked once per context
+	prod := source
+	prod.Kubeconfig = &configv1.KubeconfigReference{Context: "admin@prod", Cluster: "prod"}
+	staging := source
+	staging.Kubeconfig = &configv1.KubeconfigReference{Context: "admin@staging", Cluster: "prod"}
+	g.Expect(certificateChecksumSeed(prod, cert)).NotTo(Equal(certificateChecksumSeed(staging, cert)))
+	g.Expect(certificateChecksumSeed(prod, cert)).NotTo(Equal(certificateChecksumSeed(source, cert)))
+
+	// Certificates outside of kubeconfig files keep the seed they had before
+	g.Expect(certificateChecksumSeed(source, cert)).To(Equal("Secret-ops-kubeconfigs-kube-ca-kube-ca"))
+}
+
+func TestCertificateSentinelStatusSurvivesAPIRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	notBefore := metav1.NewTime(time.Now().UTC().Add(-time.Hour)).Rfc3339Copy()
+	notAfter := metav1.NewTime(time.Now().UTC().Add(24 * time.Hour)).Rfc3339Copy()
+	scanned := configv1.CertificateSentinelStatus{
+		DiscoveredCertificates: []configv1.CertificateInformation{{Namespace: "ops", Name: "tls", Kind: "Secret", NotBefore: notBefore, NotAfter: notAfter, TriggeredDaysOut: []int{30}}},
+		ExpiringCertificates:   1,
+		LastReportSent:         1600000000,
+	}
+
+	// The status read back from the API server is decoded from JSON into Local time
+	encoded, err := json.Marshal(scanned)
+	g.Expect(err).NotTo(HaveOccurred())
+	var stored configv1.CertificateSentinelStatus
+	g.Expect(json.Unmarshal(encoded, &stored)).To(Succeed())
+
+	g.Expect(certificateSentinelStatusChanged(stored, scanned)).To(BeFalse())
+
+	// Revocation times from OCSP responses and CRLs can carry fractions of a second the API server drops
+	revokedAt := time.Now().Add(-time.Hour).Add(123 * time.Millisecond)
+	revoked := *scanned.DeepCopy()
+	revoked.DiscoveredCertificates[0].Revocation = RevocationStatusFromResult(helpers.RevocationResult{Status: helpers.RevocationStatusRevoked, Source: helpers.RevocationSourceOCSP, RevokedAt: revokedAt, NextUpdate: revokedAt.Add(2 * time.Hour)}, nil)
+	encoded, err = json.Marshal(revoked)
+	g.Expect(err).NotTo(HaveOccurred())
+	var storedRevoked configv1.CertificateSentinelStatus
+	g.Expect(json.Unmarshal(encoded, &storedRevoked)).To(Succeed())
+	g.Expect(certificateSentinelStatusChanged(storedRevoked, revoked)).To(BeFalse())
+
+	renewed := *scanned.DeepCopy()
+	renewed.DiscoveredCertificates[0].NotAfter = metav1.NewTime(notAfter.Add(90 * 24 * time.Hour))
+	g.Expect(certificateSentinelStatusChanged(stored, renewed)).To(BeTrue())
+}
+
+func TestGetCertificateSentinelTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		spec configv1.CertificateSentinelSpec
+		want []string
+	}{
+		{
+			name: "single target",
+			spec: configv1.CertificateSentinelSpec{Target: configv1.Target{TargetName: "secrets", Kind: "Secret"}},
+			want: []string{"secrets"},
+		},
+		{
+			name: "targets only",
+			spec: configv1.CertificateSentinelSpec{Targets: []configv1.Target{{TargetName: "secrets", Kind: "Secret"}, {TargetName: "webhooks", Kind: "ValidatingWebhookConfiguration"}}},
+			want: []string{"secrets", "webhooks"},
+		},
+		{
+			name: "single target first",
+			spec: configv1.CertificateSentinelSpec{Target: configv1.Target{TargetName: "configmaps", Kind: "ConfigMap"}, Targets: []configv1.Target{{TargetName: "secrets", Kind: "Secret"}}},
+			want: []string{"configmaps", "secrets"},
+		},
+		{
+			name: "no targets",
+			spec: configv1.CertificateSentinelSpec{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var names []string
+			for _, target := range GetCertificateSentinelTargets(tt.spec) {
+				names = append(names, target.TargetName)
+			}
+			g.Expect(names).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestKeepFailedTargetCertificates(t *testing.T) {
+	g := NewWithT(t)
+	previous := []configv1.CertificateInformation{
+		{TargetName: "secrets", Name: "scanned-tls"},
+		{TargetName: "cluster-east", Name: "east-tls", TriggeredDaysOut: []int{30}},
+		{TargetName: "cluster-east", Name: "east-ca"},
+		{TargetName: "cluster-west", Name: "west-tls", TriggeredDaysOut: []int{30}},
+	}
+	statusLists := configv1.CertificateSentinelStatus{DiscoveredCertificates: []configv1.CertificateInformation{{TargetName: "secrets", Name: "rescanned-tls"}}}
+
+	// Only the failed target keeps its previous certificates, the others are replaced by what this scan found
+	g.Expect(keepFailedTargetCertificates(previous, []string{"cluster-east"}, &statusLists)).To(Equal(1))
+	var names []string
+	for _, certInfo := range statusLists.DiscoveredCertificates {
+		names = append(names, certInfo.Name)
+	}
+	g.Expect(names).To(Equal([]string{"rescanned-tls", "east-tls", "east-ca"}))
+
+	g.Expect(keepFailedTargetCertificates(previous, nil, &statusLists)).To(BeZero())
+	g.Expect(statusLists.DiscoveredCertificates).To(HaveLen(3))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/x509"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GetServiceProbeAddresses returns the in-cluster host:port addresses of the Service ports that should be dialed
+func GetServiceProbeAddresses(service corev1.Service, probePorts []int) []string {
+	var addresses []string
+	host := service.Name + "." + service.Namespace + ".svc"
+
+	for _, port := range service.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			continue
+		}
+		if isServiceProbePort(port, probePorts) {
+			addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(int(port.Port))))
+		}
+	}
+
+	return addresses
+}
+
+// isServiceProbePort checks if a Service port is in the requested list of ports, or looks like a TLS port when none were requested
+func isServiceProbePort(port corev1.ServicePort, probePorts []int) bool {
+	if len(probePorts) > 0 {
+		for _, p := range probePorts {
+			if int(port.Port) == p {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, p := range defaults.TLSProbePorts {
+		if int(port.Port) == p {
+			return true
+		}
+	}
+	portName := strings.ToLower(port.Name)
+	for _, n := range defaults.TLSProbePortNames {
+		if portName == n || strings.HasPrefix(portName, n+"-") || strings.HasSuffix(portName, "-"+n) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTLSEndpoint dials an address with the TLSProbe options and returns the certificate chain being served
+func probeTLSEndpoint(address string, tlsProbe configv1.TLSProbe) ([]*x509.Certificate, error) {
+	timeout := time.Second * time.Duration(defaults.SetDefaultInt(defaults.TLSProbeTimeout, tlsProbe.Timeout))
+	return helpers.ProbeTLSEndpoint(address, tlsProbe.ServerName, tlsProbe.StartTLS, timeout)
+}
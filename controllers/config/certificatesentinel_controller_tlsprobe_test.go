@@ -0,0 +1,96 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetServiceProbeAddresses(t *testing.T) {
+	service := func(ports ...corev1.ServicePort) corev1.Service {
+		return corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: corev1.ServiceSpec{Ports: ports}}
+	}
+
+	tests := []struct {
+		name       string
+		service    corev1.Service
+		probePorts []int
+		want       []string
+	}{
+		{
+			name:    "default TLS ports",
+			service: service(corev1.ServicePort{Name: "web", Port: 80}, corev1.ServicePort{Name: "web-tls", Port: 443}, corev1.ServicePort{Name: "admin", Port: 8443}),
+			want:    []string{"api.shop.svc:443", "api.shop.svc:8443"},
+		},
+		{
+			name:    "TLS port names",
+			service: service(corev1.ServicePort{Name: "https", Port: 9000}, corev1.ServicePort{Name: "grpc-tls", Port: 9001}, corev1.ServicePort{Name: "tls-metrics", Port: 9002}, corev1.ServicePort{Name: "metrics", Port: 9003}),
+			want:    []string{"api.shop.svc:9000", "api.shop.svc:9001", "api.shop.svc:9002"},
+		},
+		{
+			name:       "requested ports only",
+			service:    service(corev1.ServicePort{Name: "https", Port: 443}, corev1.ServicePort{Name: "ldaps", Port: 636}),
+			probePorts: []int{636},
+			want:       []string{"api.shop.svc:636"},
+		},
+		{
+			name:    "UDP ports are skipped",
+			service: service(corev1.ServicePort{Name: "https", Port: 443, Protocol: corev1.ProtocolUDP}, corev1.ServicePort{Name: "https-tcp", Port: 8443, Protocol: corev1.ProtocolTCP}),
+			want:    []string{"api.shop.svc:8443"},
+		},
+		{
+			name:    "no TLS ports",
+			service: service(corev1.ServicePort{Name: "http", Port: 80}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(GetServiceProbeAddresses(tt.service, tt.probePorts)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestScanTargetProbesEndpointsWithoutServiceAccount(t *testing.T) {
+	g := NewWithT(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The ServiceAccount does not exist, which only matters to targets that use the API
+	r := &CertificateSentinelReconciler{Client: fake.NewClientBuilder().Build()}
+	target := configv1.Target{TargetName: "endpoints", Kind: "TLSEndpoint", ServiceAccount: "missing", TLSProbe: configv1.TLSProbe{Endpoints: []string{server.Listener.Addr().String()}}}
+	statusLists := configv1.CertificateSentinelStatus{}
+	_, err := r.scanTarget(target, nil, nil, "sentinel", "https://api.cluster.example.com:6443", "/api", 60, &[]string{}, &statusLists)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(statusLists.DiscoveredCertificates).To(HaveLen(1))
+	g.Expect(statusLists.DiscoveredCertificates[0].TargetName).To(Equal("endpoints"))
+	g.Expect(statusLists.DiscoveredCertificates[0].Name).To(Equal(server.Listener.Addr().String()))
+
+	// Targets that use the API still need the ServiceAccount token
+	target.Kind = "Secret"
+	_, err = r.scanTarget(target, nil, nil, "sentinel", "https://api.cluster.example.com:6443", "/api", 60, &[]string{}, &statusLists)
+	g.Expect(err).To(Equal(errMissingServiceAccountToken))
+}
@@ -20,8 +20,8 @@ import (
 	"bytes"
 	"context"
 	"crypto/x509"
-	"reflect"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 
 	corev1 "k8s.io/api/core/v1"
@@ -36,6 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"strconv"
+	"strings"
 	"time"
 
 	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
@@ -132,6 +133,9 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	targetAPIVersion := keystoreSentinel.Spec.Target.APIVersion
 	targetDaysOut := keystoreSentinel.Spec.Target.DaysOut
 	timeOut := DaysOutToTimeOut(targetDaysOut)
+	severities := TargetSeverities(keystoreSentinel.Spec.Target.Severities)
+	policy := TargetPolicy(keystoreSentinel.Spec.Target.Policy)
+	rules := CompileSentinelRules(keystoreSentinel.Spec.Rules, LggrK)
 
 	targetNamespaceLabels := keystoreSentinel.Spec.Target.NamespaceLabels
 	targetLabels := keystoreSentinel.Spec.Target.TargetLabels
@@ -190,7 +194,7 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
-	effectiveNamespaces, _ := SetupNamespaceSlice(keystoreSentinel.Spec.Target.Namespaces, cl, LggrK, serviceAccount, targetNamespaceLabelSelector, scanningInterval)
+	effectiveNamespaces, _ := SetupNamespaceSlice(keystoreSentinel.Spec.Target.Namespaces, NamespaceExclusions(keystoreSentinel.Spec.Target.ExcludeNamespaces, keystoreSentinel.Spec.Target.ExcludeSystemNamespaces), cl, LggrK, serviceAccount, targetNamespaceLabelSelector, scanningInterval)
 
 	// Loop through the namespaces in scope for this target
 	for _, el := range effectiveNamespaces {
@@ -218,64 +222,30 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			for _, e := range secretList.Items {
 				secretType := string(e.Type)
 				if secretType == string(corev1.SecretTypeOpaque) || secretType == string(corev1.SecretTypeTLS) {
+					objectAnnotations := GetObjectAnnotations(e.Annotations, "secret/"+e.Name+" in namespace/"+el, LggrK)
+					if objectAnnotations.Ignore {
+						LogWithLevel("Ignoring annotated secret/"+e.Name+" in namespace/"+el, 3, LggrK)
+						continue
+					}
+					objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
 					LogWithLevel("Getting secret/"+e.Name+" in namespace/"+el+" (type="+secretType+")", 3, LggrK)
 
 					secretItem, _ := GetSecret(string(e.Name), el, cl)
 
 					// Get the actual secret data
 					for k, s := range secretItem.Data {
+						if objectAnnotations.IgnoresKey(k) {
+							continue
+						}
 						// Store the secret as a base64 decoded string from the byte slice
 						//sDataStr := string(s)
-						keystoreAtRisk = false
-
-						keystoreObj, err := ReadKeyStoreFromBytes(s, passwordBytes)
-
-						if err != nil {
-							// No JKS object found in this secret
-						} else {
-							// Keystore is found
+						keystoreFound, expiringCount := processDiscoveredKeystore(s, passwordBytes, objectTimeOut, severities, keystoreSentinel.Spec.Target.IncludeDetails, policy, rules, el, e.Name, k, targetKind, targetAPIVersion, objectAnnotations.Owner, &CertHashList, &statusLists)
+						if keystoreFound {
 							LogWithLevel("KEYSTORE FOUND! - ns/"+el+" - secret/"+string(e.Name)+" - key:"+k, 3, LggrK)
 							keystoreCount++
-
-							certs, err := ProcessKeystoreIntoCertificates(keystoreObj)
-							if err != nil {
-								LggrK.Error(err, "Failed to process keystore into certificates!")
-							}
-							for keystoreAlias, certSlice := range certs {
-
-								for _, cert := range certSlice {
-									// Check to see if this has already been added
-									sha_str := createUniqueCertificateChecksum(targetKind+"-"+el+"-"+e.Name+"-"+cert.Subject.CommonName+"-"+cert.Issuer.CommonName, &cert)
-
-									if defaults.ContainsString(CertHashList, sha_str) {
-										// Skipping Certificate
-										LogWithLevel("Already found "+sha_str, 3, LggrK)
-									} else {
-										// Add + Process
-										LogWithLevel("Adding "+sha_str, 3, LggrK)
-										CertHashList = append(CertHashList, sha_str)
-
-										discovered, messages := helpers.ParseKeystoreCertificateIntoObjects(&cert, timeOut, el, e.Name, k, targetKind, targetAPIVersion, keystoreAlias)
-										// Loop through passed messages for log level 3
-										for _, m := range messages {
-											LogWithLevel(m, 3, LggrK)
-										}
-
-										// Add discovered keystore to DiscoveredKeystore
-										if len(discovered) != 0 {
-											for _, iv := range discovered {
-												if len(iv.TriggeredDaysOut) > 0 {
-													expiredKeystoreCertificatesCount++
-													keystoreAtRisk = true
-												}
-												statusLists.DiscoveredKeystoreCertificates = append(statusLists.DiscoveredKeystoreCertificates, iv)
-											}
-										}
-									}
-
-								}
-							}
 						}
+						expiredKeystoreCertificatesCount += expiringCount
+						keystoreAtRisk = expiringCount > 0
 
 						if keystoreAtRisk {
 							expiredKeystoreCount++
@@ -296,70 +266,72 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 			// Loop through ConfigMaps
 			for _, e := range configMapList.Items {
+				objectAnnotations := GetObjectAnnotations(e.Annotations, "configmap/"+e.Name+" in namespace/"+el, LggrK)
+				if objectAnnotations.Ignore {
+					LogWithLevel("Ignoring annotated configmap/"+e.Name+" in namespace/"+el, 3, LggrK)
+					continue
+				}
+				objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
 				LogWithLevel("Getting configmap/"+e.Name+" in namespace/"+el, 3, LggrK)
 				configMapItem, _ := GetConfigMap(string(e.Name), el, cl)
 
 				// Loop through the actual ConfigMap data
 				for k, cm := range configMapItem.Data {
+					if objectAnnotations.IgnoresKey(k) {
+						continue
+					}
 
-					keystoreAtRisk = false
-
-					keystoreObj, err := ReadKeyStoreFromBytes([]byte(cm), passwordBytes)
-
-					if err != nil {
-						// No JKS object found in this configmap
-					} else {
-						// Keystore is found
+					keystoreFound, expiringCount := processDiscoveredKeystore([]byte(cm), passwordBytes, objectTimeOut, severities, keystoreSentinel.Spec.Target.IncludeDetails, policy, rules, el, e.Name, k, targetKind, targetAPIVersion, objectAnnotations.Owner, &CertHashList, &statusLists)
+					if keystoreFound {
 						LogWithLevel("KEYSTORE FOUND! - ns/"+el+" - configmap/"+string(e.Name)+" - key:"+k, 3, LggrK)
 						keystoreCount++
-
-						certs, err := ProcessKeystoreIntoCertificates(keystoreObj)
-						if err != nil {
-							LggrK.Error(err, "Failed to process keystore into certificates!")
-						}
-
-						for keystoreAlias, certSlice := range certs {
-							for _, cert := range certSlice {
-								// Check to see if this has already been added
-								sha_str := createUniqueCertificateChecksum(targetKind+"-"+el+"-"+e.Name+"-"+cert.Subject.CommonName+"-"+cert.Issuer.CommonName, &cert)
-
-								if defaults.ContainsString(CertHashList, sha_str) {
-									// Skipping Certificate
-									LogWithLevel("Already found "+sha_str, 3, LggrK)
-								} else {
-									// Add + Process
-									LogWithLevel("Adding "+sha_str, 3, LggrK)
-									CertHashList = append(CertHashList, sha_str)
-
-									discovered, messages := helpers.ParseKeystoreCertificateIntoObjects(&cert, timeOut, el, e.Name, k, targetKind, targetAPIVersion, keystoreAlias)
-									// Loop through passed messages for log level 3
-									for _, m := range messages {
-										LogWithLevel(m, 3, LggrK)
-									}
-
-									// Add discovered keystore to DiscoveredKeystore
-									if len(discovered) != 0 {
-										for _, iv := range discovered {
-											if len(iv.TriggeredDaysOut) > 0 {
-												expiredKeystoreCertificatesCount++
-												keystoreAtRisk = true
-											}
-											//decodedCertificates = append(decodedCertificates, cert)
-											statusLists.DiscoveredKeystoreCertificates = append(statusLists.DiscoveredKeystoreCertificates, iv)
-										}
-									}
-								}
-							}
-						}
 					}
+					expiredKeystoreCertificatesCount += expiringCount
+					keystoreAtRisk = expiringCount > 0
 
 					if keystoreAtRisk {
 						expiredKeystoreCount++
 					}
 				}
 			}
-		//=========================== DEFAULT - INVALID KIND
+		//=========================== DEFAULT - GENERIC KIND WITH DATA SELECTORS
 		default:
+			if len(keystoreSentinel.Spec.Target.DataSelectors) > 0 {
+				LogWithLevel("Checking for access to "+targetKind+" in ns/"+el, 3, LggrK)
+				// Get the list of objects of this Kind in this namespace
+				objectList, err := GetUnstructuredList(targetAPIVersion, targetKind, targetListOptions, cl)
+				if err != nil {
+					LggrK.Error(err, "Failed to list "+targetKind+" in ns/"+el)
+				}
+
+				// Loop through the objects
+				for _, e := range objectList.Items {
+					objectAnnotations := GetObjectAnnotations(e.GetAnnotations(), strings.ToLower(targetKind)+"/"+e.GetName()+" in namespace/"+el, LggrK)
+					if objectAnnotations.Ignore {
+						LogWithLevel("Ignoring annotated "+strings.ToLower(targetKind)+"/"+e.GetName()+" in namespace/"+el, 3, LggrK)
+						continue
+					}
+					objectTimeOut := ObjectTimeOut(timeOut, objectAnnotations)
+
+					// Loop through the data located by the DataSelectors
+					for _, sd := range SelectDataFromObject(e, keystoreSentinel.Spec.Target.DataSelectors) {
+						if objectAnnotations.IgnoresKey(sd.DataKey) {
+							continue
+						}
+						keystoreFound, expiringCount := processDiscoveredKeystore(sd.Data, passwordBytes, objectTimeOut, severities, keystoreSentinel.Spec.Target.IncludeDetails, policy, rules, el, e.GetName(), sd.DataKey, targetKind, targetAPIVersion, objectAnnotations.Owner, &CertHashList, &statusLists)
+						if keystoreFound {
+							LogWithLevel("KEYSTORE FOUND! - ns/"+el+" - "+strings.ToLower(targetKind)+"/"+e.GetName()+" - key:"+sd.DataKey, 3, LggrK)
+							keystoreCount++
+						}
+						expiredKeystoreCertificatesCount += expiringCount
+						if expiringCount > 0 {
+							expiredKeystoreCount++
+						}
+					}
+				}
+				break
+			}
+
 			// Unsupported Object Kind
 			LggrK.Info("Invalid Target Kind!")
 			LggrK.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
@@ -369,12 +341,7 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Set updater check vars
-	statusChanged := true
-	oldDiscoveredKeystoreCertificates := keystoreSentinel.Status.DiscoveredKeystoreCertificates
-	oldLastReportSent := keystoreSentinel.Status.LastReportSent
-	oldExpiringCertificates := keystoreSentinel.Status.ExpiringCertificates
-	oldKeystoresAtRisk := keystoreSentinel.Status.KeystoresAtRisk
-	oldTotalKeystoresFound := keystoreSentinel.Status.TotalKeystoresFound
+	oldStatus := *keystoreSentinel.Status.DeepCopy()
 
 	// Merge the Certificates into the .status of the KeystoreSentinel object
 	keystoreSentinel.Status.DiscoveredKeystoreCertificates = statusLists.DiscoveredKeystoreCertificates
@@ -383,16 +350,21 @@ func (r *KeystoreSentinelReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	keystoreSentinel.Status.TotalKeystoresFound = keystoreCount
 
 	// Process reports if needed, only if there are new certificates at risk
-	if expiredKeystoreCertificatesCount > 0 {
-		keystoreSentinel.Status.LastReportSent = processKeystoreReport(*keystoreSentinel, LggrK, r.Client)
+	if hasKeystoreCertificatesAtRisk(keystoreSentinel.Status.DiscoveredKeystoreCertificates) {
+		lastReportSent, reportDeliveries, reportAttempted, reportErr := processKeystoreReport(*keystoreSentinel, LggrK, r.Client)
+		keystoreSentinel.Status.ReportDeliveries = reportDeliveries
+		keystoreSentinel.Status.LastReportSent = lastReportSent
+		if reportAttempted {
+			keystoreSentinel.Status.LastReportAttempt = time.Now().Unix()
+			keystoreSentinel.Status.LastReportError = ""
+			if reportErr != nil {
+				keystoreSentinel.Status.LastReportError = reportErr.Error()
+			}
+		}
 	}
 
 	// Check the difference in structs
-	if reflect.DeepEqual(oldDiscoveredKeystoreCertificates, keystoreSentinel.Status.DiscoveredKeystoreCertificates) && oldLastReportSent == keystoreSentinel.Status.LastReportSent && oldExpiringCertificates == keystoreSentinel.Status.ExpiringCertificates && oldKeystoresAtRisk == keystoreSentinel.Status.KeystoresAtRisk && oldTotalKeystoresFound == keystoreSentinel.Status.TotalKeystoresFound {
-		statusChanged = false
-	}
-
-	if statusChanged {
+	if keystoreSentinelStatusChanged(oldStatus, keystoreSentinel.Status) {
 		err = r.Status().Update(ctx, keystoreSentinel)
 		if err != nil {
 			LggrK.Error(err, "Failed to update KeystoreSentinel status")
@@ -421,6 +393,75 @@ func (r *KeystoreSentinelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// keystoreSentinelStatusChanged compares the .status semantically, the times read back from the API server are in Local time while freshly scanned ones are in UTC
+func keystoreSentinelStatusChanged(oldStatus configv1.KeystoreSentinelStatus, newStatus configv1.KeystoreSentinelStatus) bool {
+	return !equality.Semantic.DeepEqual(oldStatus, newStatus)
+}
+
+// processDiscoveredKeystore attempts to open the byte slice as a keystore and adds any certificates not yet seen into the .status list, returning if a keystore was found and how many of its certificates are at risk of expiring
+func processDiscoveredKeystore(keystoreBytes []byte, passwordBytes []byte, timeOut []configv1.TimeSlice, severities []configv1.SeverityThreshold, includeDetails bool, policy *configv1.CertificatePolicy, rules []helpers.PolicyRuleProgram, namespace string, name string, dataKey string, kind string, apiVersion string, owner string, certHashList *[]string, statusLists *configv1.KeystoreSentinelStatus) (bool, int) {
+	expiredKeystoreCertificatesCount := 0
+
+	keystoreObj, err := ReadKeyStoreFromBytes(keystoreBytes, passwordBytes)
+	if err != nil {
+		// No JKS object found in this data
+		return false, 0
+	}
+
+	certs, err := ProcessKeystoreIntoCertificates(keystoreObj)
+	if err != nil {
+		LggrK.Error(err, "Failed to process keystore into certificates!")
+	}
+
+	for keystoreAlias, certSlice := range certs {
+		for i := range certSlice {
+			cert := certSlice[i]
+			// Check to see if this has already been added
+			sha_str := createUniqueCertificateChecksum(kind+"-"+namespace+"-"+name+"-"+cert.Subject.CommonName+"-"+cert.Issuer.CommonName, &cert)
+
+			if defaults.ContainsString(*certHashList, sha_str) {
+				// Skipping Certificate
+				LogWithLevel("Already found "+sha_str, 3, LggrK)
+			} else {
+				// Add + Process
+				LogWithLevel("Adding "+sha_str, 3, LggrK)
+				*certHashList = append(*certHashList, sha_str)
+
+				discovered, messages := helpers.ParseKeystoreCertificateIntoObjects(&cert, timeOut, namespace, name, dataKey, kind, apiVersion, keystoreAlias)
+				// Loop through passed messages for log level 3
+				for _, m := range messages {
+					LogWithLevel(m, 3, LggrK)
+				}
+
+				// Add discovered keystore to DiscoveredKeystore
+				for _, iv := range discovered {
+					iv.Owner = owner
+					iv.Severity = helpers.CertificateSeverity(cert.NotAfter, time.Now(), severities)
+					if includeDetails {
+						iv.Details = helpers.ParseCertificateDetails(&cert)
+					}
+					if policy != nil {
+						iv.PolicyViolations = helpers.EvaluateCertificatePolicy(&cert, namespace, *policy)
+					}
+					if len(rules) > 0 {
+						ruleViolations, errs := helpers.EvaluatePolicyRules(rules, helpers.CertificateRuleVariables(&cert, namespace, name, kind, dataKey, time.Now()))
+						for _, err := range errs {
+							LogWithLevel("Failed to evaluate policy "+err.Error(), 2, LggrK)
+						}
+						iv.PolicyViolations = append(iv.PolicyViolations, ruleViolations...)
+					}
+					if len(iv.TriggeredDaysOut) > 0 {
+						expiredKeystoreCertificatesCount++
+					}
+					statusLists.DiscoveredKeystoreCertificates = append(statusLists.DiscoveredKeystoreCertificates, iv)
+				}
+			}
+		}
+	}
+
+	return true, expiredKeystoreCertificatesCount
+}
+
 // ReadKeyStoreFromBytes takes in a byte slice and password and decodes the
 func ReadKeyStoreFromBytes(byteData []byte, password []byte) (keystore.KeyStore, error) {
 	f := bytes.NewReader(byteData)
@@ -19,12 +19,12 @@ package config
 import (
 	"bytes"
 	"fmt"
+	htmltemplate "html/template"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
 	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
 	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
-	corev1 "k8s.io/api/core/v1"
 
 	"strconv"
 	"strings"
@@ -36,43 +36,68 @@ import (
 )
 
 // processKeystoreReport processes reports for the KeystoreSentinel CRD
-func processKeystoreReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger, clnt client.Client) int64 {
+// It returns the time the last report was delivered, which only advances when every report was delivered, if a delivery was attempted, and any delivery error
+func processKeystoreReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger, clnt client.Client) (int64, []configv1.ReportDelivery, bool, error) {
 	// Set up variables
 	currentUnixTime := time.Now().Unix()
 	lastReportSentTime := defaults.SetDefaultInt64(currentUnixTime, keystoreSentinel.Status.LastReportSent)
-	effectiveReportInterval := defaults.SetDefaultString(defaults.ReportInterval, keystoreSentinel.Spec.Alert.AlertConfiguration.ReportInterval)
 
-	// Add the times together
-	addedTime := (lastReportSentTime + intervalToSeconds(effectiveReportInterval))
+	if isReportDue(keystoreSentinel.Spec.Alert.AlertConfiguration, lastReportSentTime, currentUnixTime, lggr) {
+		// List the most urgent certificates first
+		keystoreSentinel.Status.DiscoveredKeystoreCertificates = sortKeystoreCertificatesByUrgency(keystoreSentinel.Status.DiscoveredKeystoreCertificates)
+
+		// Leave the certificates below the alert's minimum severity out of the report
+		minimumSeverity := keystoreSentinel.Spec.Alert.AlertConfiguration.MinimumSeverity
+		if minimumSeverity != "" {
+			keystoreSentinel.Status.DiscoveredKeystoreCertificates = filterKeystoreCertificatesBySeverity(keystoreSentinel.Status.DiscoveredKeystoreCertificates, minimumSeverity)
+			if !hasKeystoreCertificatesAtRisk(keystoreSentinel.Status.DiscoveredKeystoreCertificates) {
+				LogWithLevel("No certificates at or above the "+minimumSeverity+" severity, skipping report for "+keystoreSentinel.Spec.Alert.AlertName, 2, lggr)
+				// Nothing was sent, so the previous LastReportSent is kept and the report goes out once a certificate reaches the minimum severity
+				return keystoreSentinel.Status.LastReportSent, keystoreSentinel.Status.ReportDeliveries, false, nil
+			}
+		}
 
-	// Next expected time to send is before the current time, overdue to send
-	if (addedTime < time.Now().Unix()) || (lastReportSentTime == currentUnixTime) {
 		LogWithLevel("Dispatching report for "+keystoreSentinel.Spec.Alert.AlertName, 2, lggr)
+		reportTemplates := loadReportTemplates(keystoreReportTemplates(), keystoreSentinel.Spec.Alert, keystoreSentinel.Namespace, lggr, clnt)
 
 		// Send out alert based on alert type
 		switch keystoreSentinel.Spec.Alert.AlertType {
 		case "smtp":
-			createKeystoreSMTPReport(keystoreSentinel, lggr, clnt)
+			// Only the routes not yet delivered in this report cycle are sent, so recipients that already have the report are not sent it again
+			routes := routeKeystoreSMTPReport(keystoreSentinel, lggr, clnt)
+			var recipients [][]string
+			for _, route := range routes {
+				recipients = append(recipients, route.To)
+			}
+			deliveries, attempted, err := deliverReportRoutes(recipients, keystoreSentinel.Status.ReportDeliveries, keystoreSentinel.Spec.Alert.AlertConfiguration, time.Now(), func(i int) error {
+				_, err := createKeystoreSMTPReport(routes[i].KeystoreSentinel, routes[i].To, reportTemplates, lggr, clnt)
+				return err
+			}, lggr)
+			// Keep the previous LastReportSent until every route is delivered
+			if deliveries != nil {
+				return keystoreSentinel.Status.LastReportSent, deliveries, attempted, err
+			}
 		case "logger":
-			loggr := createKeystoreLoggerReport(keystoreSentinel, lggr)
+			loggr := createKeystoreLoggerReport(keystoreSentinel, reportTemplates, lggr)
 			lggr.Info(loggr)
 		default:
-			loggr := createKeystoreLoggerReport(keystoreSentinel, lggr)
+			loggr := createKeystoreLoggerReport(keystoreSentinel, reportTemplates, lggr)
 			lggr.Info(loggr)
 		}
 
-		return time.Now().Unix()
+		return time.Now().Unix(), nil, true, nil
 	}
-	return lastReportSentTime
+	// Keep the previous LastReportSent, which is unset when the first report is held for quiet hours
+	return keystoreSentinel.Status.LastReportSent, keystoreSentinel.Status.ReportDeliveries, false, nil
 }
 
 // createKeystoreLoggerReport loops through KeystoreSentinel.Status and creates a stdout report
-func createKeystoreLoggerReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger) string {
-	return createKeystoreTextTableReport(keystoreSentinel, lggr)
+func createKeystoreLoggerReport(keystoreSentinel configv1.KeystoreSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
+	return createKeystoreTextTableReport(keystoreSentinel, reportTemplates, lggr)
 }
 
 // createKeystoreTextTableReport creates a Text-based table of the report, used in logger reports and text-based SMTP reports
-func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger) string {
+func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
 	currentConfig, _ := config.GetConfig()
 	clusterEndpoint := currentConfig.Host
 	apiPath := currentConfig.APIPath
@@ -91,12 +116,15 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 	CACNLongest := "Signing CA CN"
 	ExpirationDateLongest := "Expiration Date"
 	TriggeredDaysOutLongest := "Triggered Days Out"
+	PolicyViolationsLongest := "Policy Violations"
 
 	// Loop through the .status.DiscoveredCertificates
 	for _, certInfo := range keystoreSentinel.Status.DiscoveredKeystoreCertificates {
 		// If this is an expired certificate
-		if len(certInfo.TriggeredDaysOut) > 0 {
-			expiredCertificateCount++
+		if isKeystoreCertificateAtRisk(certInfo) {
+			if len(certInfo.TriggeredDaysOut) > 0 {
+				expiredCertificateCount++
+			}
 			// Set up Logger Lines for length
 			APIVersionLongest = helpers.ReturnLonger(APIVersionLongest, certInfo.APIVersion)
 			KindLongest = helpers.ReturnLonger(KindLongest, certInfo.Kind)
@@ -107,8 +135,9 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 			CertCNLongest = helpers.ReturnLonger(CertCNLongest, certInfo.CommonName)
 			IsCALongest = helpers.ReturnLonger(IsCALongest, strconv.FormatBool(certInfo.IsCertificateAuthority))
 			CACNLongest = helpers.ReturnLonger(CACNLongest, certInfo.CertificateAuthorityCommonName)
-			ExpirationDateLongest = helpers.ReturnLonger(ExpirationDateLongest, certInfo.Expiration)
+			ExpirationDateLongest = helpers.ReturnLonger(ExpirationDateLongest, reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z))
 			TriggeredDaysOutLongest = helpers.ReturnLonger(TriggeredDaysOutLongest, strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"))
+			PolicyViolationsLongest = helpers.ReturnLonger(PolicyViolationsLongest, joinPolicyViolationRules(certInfo.PolicyViolations))
 		}
 	}
 
@@ -124,12 +153,13 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 	CACNLength := len(CACNLongest)
 	ExpirationDateLength := len(ExpirationDateLongest)
 	TriggeredDaysOutLength := len(TriggeredDaysOutLongest)
-	TotalLineLength := (APIVersionLength + KindLength + NamespaceLength + NameLength + DataKeyLength + KeystoreAliasLength + CertCNLength + IsCALength + CACNLength + ExpirationDateLength + TriggeredDaysOutLength + 34)
+	PolicyViolationsLength := len(PolicyViolationsLongest)
+	TotalLineLength := (APIVersionLength + KindLength + NamespaceLength + NameLength + DataKeyLength + KeystoreAliasLength + CertCNLength + IsCALength + CACNLength + ExpirationDateLength + TriggeredDaysOutLength + PolicyViolationsLength + 37)
 	LineBreak := helpers.StrPad("-", TotalLineLength, "-", "BOTH")
 
 	// Loop through the .status.DiscoveredKeystoreCertificates
 	for _, certInfo := range keystoreSentinel.Status.DiscoveredKeystoreCertificates {
-		if len(certInfo.TriggeredDaysOut) > 0 {
+		if isKeystoreCertificateAtRisk(certInfo) {
 			// Set up Logger Lines
 			loggerReportLineStructure := LoggerKeystoreReportLineStructure{
 				APIVersion:                     helpers.StrPad(certInfo.APIVersion, APIVersionLength, " ", "BOTH"),
@@ -141,11 +171,15 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 				CommonName:                     helpers.StrPad(certInfo.CommonName, CertCNLength, " ", "BOTH"),
 				IsCA:                           helpers.StrPad(strconv.FormatBool(certInfo.IsCertificateAuthority), IsCALength, " ", "BOTH"),
 				CertificateAuthorityCommonName: helpers.StrPad(certInfo.CertificateAuthorityCommonName, CACNLength, " ", "BOTH"),
-				ExpirationDate:                 helpers.StrPad(certInfo.Expiration, ExpirationDateLength, " ", "BOTH"),
+				ExpirationDate:                 helpers.StrPad(reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z), ExpirationDateLength, " ", "BOTH"),
+				NotBefore:                      certInfo.NotBefore.Time,
+				NotAfter:                       certInfo.NotAfter.Time,
+				Details:                        certInfo.Details,
 				TriggeredDaysOut:               helpers.StrPad(strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"), TriggeredDaysOutLength, " ", "BOTH"),
+				PolicyViolations:               helpers.StrPad(joinPolicyViolationRules(certInfo.PolicyViolations), PolicyViolationsLength, " ", "BOTH"),
 			}
 			lineBuf := new(bytes.Buffer)
-			loggerLineTemplate, err := template.New("loggerLine").Parse(LoggerKeystoreReportLine)
+			loggerLineTemplate, err := template.New("loggerLine").Parse(reportTemplates.TextRow)
 			if err != nil {
 				lggr.Error(err, "Error parsing loggerLineTemplate template!")
 			}
@@ -171,9 +205,10 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 		CertificateAuthorityCommonName: helpers.StrPad("Signing CA CN", CACNLength, " ", "BOTH"),
 		ExpirationDate:                 helpers.StrPad("Expiration Date", ExpirationDateLength, " ", "BOTH"),
 		TriggeredDaysOut:               helpers.StrPad("Triggered Days Out", TriggeredDaysOutLength, " ", "BOTH"),
+		PolicyViolations:               helpers.StrPad("Policy Violations", PolicyViolationsLength, " ", "BOTH"),
 	}
 	headerBuf := new(bytes.Buffer)
-	loggerHeaderTemplate, err := template.New("loggerHeader").Parse(LoggerKeystoreReportHeader)
+	loggerHeaderTemplate, err := template.New("loggerHeader").Parse(reportTemplates.TextHeader)
 	if err != nil {
 		lggr.Error(err, "Error parsing loggerHeaderTemplate template!", 1, lggr)
 	}
@@ -199,7 +234,7 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 	}
 	// Build template
 	reportBuf := new(bytes.Buffer)
-	loggerReportTemplate, err := template.New("loggerReport").Parse(LoggerKeystoreReport)
+	loggerReportTemplate, err := template.New("loggerReport").Parse(reportTemplates.Text)
 	if err != nil {
 		lggr.Error(err, "Error parsing loggerReportTemplate template!")
 	}
@@ -212,60 +247,73 @@ func createKeystoreTextTableReport(keystoreSentinel configv1.KeystoreSentinel, l
 	return reportBuf.String()
 }
 
-// createKeystoreSMTPReport loops through CertificateSentinel.Status and sends an email report
-func createKeystoreSMTPReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger, clnt client.Client) string {
+// createKeystoreSMTPReport loops through CertificateSentinel.Status and sends an email report to the provided recipients
+func createKeystoreSMTPReport(keystoreSentinel configv1.KeystoreSentinel, to []string, reportTemplates ReportTemplates, lggr logr.Logger, clnt client.Client) (string, error) {
 
-	textEmailReport := tableTextReportToBasicHTMLReport(createKeystoreTextTableReport(keystoreSentinel, lggr))
-	htmlEmailReport := createKeystoreSMTPHTMLReport(keystoreSentinel, lggr)
+	textEmailReport := createKeystoreTextTableReport(keystoreSentinel, reportTemplates, lggr)
+	htmlEmailReport := createKeystoreSMTPHTMLReport(keystoreSentinel, reportTemplates, lggr)
+	attachments := createReportAttachments(keystoreSentinel.Spec.Alert.AlertConfiguration.SMTPAttachments, "keystore-certificates-at-risk", keystoreReportRows(keystoreSentinel.Status.DiscoveredKeystoreCertificates), atRiskKeystoreCertificates(keystoreSentinel.Status.DiscoveredKeystoreCertificates), lggr)
+	currentConfig, _ := config.GetConfig()
+	var triggeredDaysOut [][]int
+	severity := ""
+	atRisk := atRiskKeystoreCertificates(keystoreSentinel.Status.DiscoveredKeystoreCertificates)
+	expiringCerts := 0
+	policyViolations := 0
+	for _, certInfo := range atRisk {
+		if len(certInfo.TriggeredDaysOut) > 0 {
+			expiringCerts++
+			triggeredDaysOut = append(triggeredDaysOut, certInfo.TriggeredDaysOut)
+			if helpers.SeverityRank(certInfo.Severity) > helpers.SeverityRank(severity) {
+				severity = certInfo.Severity
+			}
+		}
+		for _, violation := range certInfo.PolicyViolations {
+			policyViolations++
+			if helpers.SeverityRank(violation.Severity) > helpers.SeverityRank(severity) {
+				severity = violation.Severity
+			}
+		}
+	}
+	subject := renderReportSubject(reportTemplates.Subject, ReportSubjectStructure{
+		Namespace:          keystoreSentinel.Namespace,
+		Name:               keystoreSentinel.Name,
+		AlertName:          keystoreSentinel.Spec.Alert.AlertName,
+		ClusterName:        reportClusterName(keystoreSentinel.Spec.Alert.AlertConfiguration, currentConfig.Host),
+		ClusterAPIEndpoint: currentConfig.Host + currentConfig.APIPath,
+		TotalCerts:         strconv.Itoa(len(keystoreSentinel.Status.DiscoveredKeystoreCertificates)),
+		ExpiringCerts:      strconv.Itoa(expiringCerts),
+		PolicyViolations:   strconv.Itoa(policyViolations),
+		MostUrgentDaysOut:  mostUrgentDaysOut(triggeredDaysOut),
+		Severity:           severity,
+	}, lggr)
 
 	// Loop through the alerts
 	alert := keystoreSentinel.Spec.Alert
 	if alert.AlertType == "smtp" {
-		// Set up basic SMTP vars
-		var username string
-		var password string
-		var identity string
-		var cramSecret string
-
-		// Set defaults
-		useTLS := defaults.SetDefaultBool(&defaults.SMTPAuthUseSSL, alert.AlertConfiguration.SMTPAuthUseSSL)
-		useSTARTTLS := defaults.SetDefaultBool(&defaults.SMTPAuthUseSTARTTLS, alert.AlertConfiguration.SMTPAuthUseSTARTTLS)
-
-		// Get SMTP Authentication Secret if the AuthType is not `none`
-		smtpAuthSecret := &corev1.Secret{}
-		if alert.AlertConfiguration.SMTPAuthType != "none" {
-			smtpAuthSecret, _ = GetSecret(alert.AlertConfiguration.SMTPAuthSecretName, keystoreSentinel.Namespace, clnt)
-		}
-
-		// Assign SMTP Auth vars where needed
-		if len(smtpAuthSecret.Data) > 0 {
-			username = string(smtpAuthSecret.Data["username"])
-			password = string(smtpAuthSecret.Data["password"])
-			identity = string(smtpAuthSecret.Data["identity"])
-			cramSecret = string(smtpAuthSecret.Data["cram"])
+		// Set up the SMTP server connection, authentication, and TLS options
+		serverConfig, err := newSMTPServerConfig(alert.AlertConfiguration, keystoreSentinel.Namespace, clnt)
+		if err != nil {
+			return textEmailReport, err
 		}
 
-		// Send the message
-		helpers.SendSMTPMail(alert.AlertConfiguration.SMTPAuthType,
-			username,
-			password,
-			identity,
-			cramSecret,
-			useTLS,
-			useSTARTTLS,
-			alert.AlertConfiguration.SMTPDestinationEmailAddresses,
+		// Send the message once, failed deliveries are retried with backoff on a later scan
+		err = helpers.SendSMTPMail(serverConfig,
+			to,
 			alert.AlertConfiguration.SMTPSenderEmailAddress,
-			alert.AlertConfiguration.SMTPEndpoint,
-			"Certificate Sentinel Operator - KeystoreSentinel Report",
+			subject,
 			textEmailReport,
-			htmlEmailReport)
+			htmlEmailReport,
+			attachments)
+		if err != nil {
+			return textEmailReport, err
+		}
 	}
 
-	return textEmailReport
+	return textEmailReport, nil
 }
 
 // createKeystoreSMTPHTMLReport creates a rich HTML-based table of the report, used in SMTP reports
-func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger) string {
+func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, reportTemplates ReportTemplates, lggr logr.Logger) string {
 
 	// Set up init vars
 	var reportLines string
@@ -277,8 +325,10 @@ func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lg
 
 	// Loop through the .status.DiscoveredCertificates
 	for iCI, certInfo := range keystoreSentinel.Status.DiscoveredKeystoreCertificates {
-		if len(certInfo.TriggeredDaysOut) > 0 {
-			expiredCertificateCount++
+		if isKeystoreCertificateAtRisk(certInfo) {
+			if len(certInfo.TriggeredDaysOut) > 0 {
+				expiredCertificateCount++
+			}
 			// Set up styles
 			var rowStyles string
 			cellStyles := "padding:6px;text-align:left;"
@@ -288,13 +338,6 @@ func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lg
 				rowStyles = "background:#FAFAFA;"
 			}
 
-			// Parse time to format
-			inputTimelayout := "2006-01-02 15:04:05 -0700 MST"
-			t, err := time.Parse(inputTimelayout, certInfo.Expiration)
-			if err != nil {
-				fmt.Printf("Error parsing time! %+v\n", err)
-			}
-
 			// Set up HTML Lines
 			htmlSMTPReportLine := HTMLKeystoreReportLineStructure{
 				APIVersion:                     certInfo.APIVersion,
@@ -306,13 +349,17 @@ func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lg
 				CommonName:                     certInfo.CommonName,
 				IsCA:                           strconv.FormatBool(certInfo.IsCertificateAuthority),
 				CertificateAuthorityCommonName: certInfo.CertificateAuthorityCommonName,
-				ExpirationDate:                 string(t.Format(time.RFC822Z)),
+				ExpirationDate:                 reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC822Z),
+				NotBefore:                      certInfo.NotBefore.Time,
+				NotAfter:                       certInfo.NotAfter.Time,
+				Details:                        certInfo.Details,
 				TriggeredDaysOut:               strings.Trim(strings.Join(strings.Fields(fmt.Sprint(certInfo.TriggeredDaysOut)), ", "), "[]"),
-				RowStyles:                      rowStyles,
-				CellStyles:                     cellStyles,
+				PolicyViolations:               joinPolicyViolationRules(certInfo.PolicyViolations),
+				RowStyles:                      htmltemplate.CSS(rowStyles),
+				CellStyles:                     htmltemplate.CSS(cellStyles),
 			}
 			lineBuf := new(bytes.Buffer)
-			htmlLineTemplate, err := template.New("tableLine").Parse(HTMLSMTPKeystoreReportLine)
+			htmlLineTemplate, err := htmltemplate.New("tableLine").Parse(reportTemplates.HTMLRow)
 			if err != nil {
 				lggr.Error(err, "Error parsing htmlSMTPReportLine template!")
 			}
@@ -342,11 +389,12 @@ func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lg
 		CertificateAuthorityCommonName: "Signing CA CN",
 		ExpirationDate:                 "Expiration Date",
 		TriggeredDaysOut:               "Triggered Days Out",
-		RowStyles:                      rowStyles,
-		CellStyles:                     cellStyles,
+		PolicyViolations:               "Policy Violations",
+		RowStyles:                      htmltemplate.CSS(rowStyles),
+		CellStyles:                     htmltemplate.CSS(cellStyles),
 	}
 	headerBuf := new(bytes.Buffer)
-	tableHeaderTemplate, err := template.New("tableHeader").Parse(HTMLSMTPKeystoreReportHeader)
+	tableHeaderTemplate, err := htmltemplate.New("tableHeader").Parse(reportTemplates.HTMLHeader)
 	if err != nil {
 		lggr.Error(err, "Error parsing tableHeaderTemplate template!")
 	}
@@ -365,13 +413,13 @@ func createKeystoreSMTPHTMLReport(keystoreSentinel configv1.KeystoreSentinel, lg
 		KeystoresAtRisk:    strconv.Itoa(keystoreSentinel.Status.KeystoresAtRisk),
 		TotalCerts:         strconv.Itoa(len(keystoreSentinel.Status.DiscoveredKeystoreCertificates)),
 		ExpiringCerts:      strconv.Itoa(keystoreSentinel.Status.ExpiringCertificates),
-		TableRows:          reportLines,
-		THead:              headerBuf.String(),
-		TFoot:              headerBuf.String(),
-		BodyDivider:        HTMLSMTPReportBodyDivider,
+		TableRows:          htmltemplate.HTML(reportLines),
+		THead:              htmltemplate.HTML(headerBuf.String()),
+		TFoot:              htmltemplate.HTML(headerBuf.String()),
+		BodyDivider:        htmltemplate.HTML(HTMLSMTPReportBodyDivider),
 	}
 	reportBuf := new(bytes.Buffer)
-	htmlReportTemplate, err := template.New("HTMLReport").Parse(HTMLSMTPKeystoreReportBody)
+	htmlReportTemplate, err := htmltemplate.New("HTMLReport").Parse(reportTemplates.HTML)
 	if err != nil {
 		lggr.Error(err, "Error parsing htmlReportTemplate template!")
 	}
@@ -16,6 +16,13 @@ limitations under the License.
 
 package config
 
+import (
+	"html/template"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
 //==================================================================================================
 // Logger Reports - Plain text based for SMTP too
 //==================================================================================================
@@ -38,10 +45,10 @@ KeystoreSentinel Report: {{ .Namespace }}/{{ .Name }} ({{ .DateSent }})
 {{ .Divider }}
 `
 
-const LoggerKeystoreReportLine = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .KeystoreAlias }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} |
+const LoggerKeystoreReportLine = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .KeystoreAlias }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} | {{ .PolicyViolations }} |
 `
 
-const LoggerKeystoreReportHeader = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .KeystoreAlias }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} |`
+const LoggerKeystoreReportHeader = `| {{ .APIVersion }} | {{ .Kind }} | {{ .Namespace }} | {{ .Name }} | {{ .Key }} | {{ .KeystoreAlias }} | {{ .CommonName }} | {{ .IsCA }} | {{ .CertificateAuthorityCommonName }} | {{ .ExpirationDate }} | {{ .TriggeredDaysOut }} | {{ .PolicyViolations }} |`
 
 // loggerReportStructure provides the overall structure to the loggerReport template
 type LoggerKeystoreReportStructure struct {
@@ -72,6 +79,7 @@ type LoggerKeystoreReportHeaderStructure struct {
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
 	TriggeredDaysOut               string
+	PolicyViolations               string
 }
 
 // loggerReportLineStructure provides the struct for the loggerReportLine template
@@ -86,7 +94,11 @@ type LoggerKeystoreReportLineStructure struct {
 	IsCA                           string
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
+	NotBefore                      time.Time
+	NotAfter                       time.Time
+	Details                        *configv1.CertificateDetails
 	TriggeredDaysOut               string
+	PolicyViolations               string
 }
 
 //==================================================================================================
@@ -152,6 +164,7 @@ const HTMLSMTPKeystoreReportLine = `<tr style="{{ .RowStyles }}">
 <td style="{{ .CellStyles }}">{{ .CertificateAuthorityCommonName }}</td>
 <td style="{{ .CellStyles }}">{{ .ExpirationDate }}</td>
 <td style="{{ .CellStyles }}">{{ .TriggeredDaysOut }}</td>
+<td style="{{ .CellStyles }}">{{ .PolicyViolations }}</td>
 </tr>`
 
 const HTMLSMTPKeystoreReportHeader = `<tr style="background:#EEE;">
@@ -166,6 +179,7 @@ const HTMLSMTPKeystoreReportHeader = `<tr style="background:#EEE;">
 <td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .CertificateAuthorityCommonName }}</td>
 <td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .ExpirationDate }}</td>
 <td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .TriggeredDaysOut }}</td>
+<td style="{{ .CellStyles }}border-bottom:1px solid #999;border-top:1px solid #999;">{{ .PolicyViolations }}</td>
 </tr>`
 
 // HTMLReportStructure provides the overall structure to the HTMLSMTPReport template
@@ -178,10 +192,10 @@ type HTMLKeystoreReportStructure struct {
 	KeystoresAtRisk    string
 	TotalCerts         string
 	ExpiringCerts      string
-	TableRows          string
-	THead              string
-	TFoot              string
-	BodyDivider        string
+	TableRows          template.HTML
+	THead              template.HTML
+	TFoot              template.HTML
+	BodyDivider        template.HTML
 }
 
 // HTMLReportLineStructure provides the struct for the htmlReportLine template
@@ -196,9 +210,13 @@ type HTMLKeystoreReportLineStructure struct {
 	IsCA                           string
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
+	NotBefore                      time.Time
+	NotAfter                       time.Time
+	Details                        *configv1.CertificateDetails
 	TriggeredDaysOut               string
-	RowStyles                      string
-	CellStyles                     string
+	PolicyViolations               string
+	RowStyles                      template.CSS
+	CellStyles                     template.CSS
 }
 
 // HTMLReportHeaderStructure provides the struct for the htmlReportHeader template
@@ -214,8 +232,9 @@ type HTMLKeystoreReportHeaderStructure struct {
 	CertificateAuthorityCommonName string
 	ExpirationDate                 string
 	TriggeredDaysOut               string
-	RowStyles                      string
-	CellStyles                     string
+	PolicyViolations               string
+	RowStyles                      template.CSS
+	CellStyles                     template.CSS
 }
 
 //==================================================================================================
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKeystoreSentinelStatusSurvivesAPIRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	notBefore := metav1.NewTime(time.Now().UTC().Add(-time.Hour)).Rfc3339Copy()
+	notAfter := metav1.NewTime(time.Now().UTC().Add(24 * time.Hour)).Rfc3339Copy()
+	scanned := configv1.KeystoreSentinelStatus{
+		DiscoveredKeystoreCertificates: []configv1.KeystoreInformation{{Namespace: "ops", Name: "keystore", Kind: "Secret", KeystoreAlias: "server", NotBefore: notBefore, NotAfter: notAfter}},
+		TotalKeystoresFound:            1,
+	}
+
+	// The status read back from the API server is decoded from JSON into Local time
+	encoded, err := json.Marshal(scanned)
+	g.Expect(err).NotTo(HaveOccurred())
+	var stored configv1.KeystoreSentinelStatus
+	g.Expect(json.Unmarshal(encoded, &stored)).To(Succeed())
+
+	g.Expect(keystoreSentinelStatusChanged(stored, scanned)).To(BeFalse())
+
+	stored.KeystoresAtRisk = 1
+	g.Expect(keystoreSentinelStatusChanged(stored, scanned)).To(BeTrue())
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
+)
+
+// createReportAttachments exports the at-risk certificates in each of the requested formats, `csv` or `json`, as SMTP attachments
+func createReportAttachments(formats []string, baseName string, csvRows [][]string, jsonData interface{}, lggr logr.Logger) []helpers.SMTPAttachment {
+	var attachments []helpers.SMTPAttachment
+
+	for _, format := range formats {
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "csv":
+			csvBuf := new(bytes.Buffer)
+			csvWriter := csv.NewWriter(csvBuf)
+			err := csvWriter.WriteAll(csvRows)
+			if err != nil {
+				lggr.Error(err, "Error creating CSV report attachment!")
+				continue
+			}
+			attachments = append(attachments, helpers.SMTPAttachment{Name: baseName + ".csv", MimeType: "text/csv", Data: csvBuf.Bytes()})
+		case "json":
+			jsonBytes, err := json.MarshalIndent(jsonData, "", "  ")
+			if err != nil {
+				lggr.Error(err, "Error creating JSON report attachment!")
+				continue
+			}
+			attachments = append(attachments, helpers.SMTPAttachment{Name: baseName + ".json", MimeType: "application/json", Data: jsonBytes})
+		default:
+			LogWithLevel("Unsupported report attachment format: "+format, 1, lggr)
+		}
+	}
+
+	return attachments
+}
+
+// atRiskCertificates returns only the certificates that have triggered a DaysOut threshold, failed a policy check, or been revoked
+func atRiskCertificates(certificates []configv1.CertificateInformation) []configv1.CertificateInformation {
+	atRisk := []configv1.CertificateInformation{}
+	for _, certInfo := range certificates {
+		if isCertificateAtRisk(certInfo) {
+			atRisk = append(atRisk, certInfo)
+		}
+	}
+	return atRisk
+}
+
+// certificateReportRows returns the header and a row for each at-risk certificate, used for the CSV attachment
+func certificateReportRows(certificates []configv1.CertificateInformation) [][]string {
+	rows := [][]string{{"Target", "APIVersion", "Kind", "Namespace", "Name", "Data Key", "Certificate CN", "Is CA", "Signing CA CN", "Expiration Date", "Days Remaining", "Expired", "Not Yet Valid", "Revoked", "Triggered Days Out", "Severity", "Policy Violations", "Owner", "Subject", "Issuer", "Subject Alternative Names", "Serial Number", "SHA-256 Fingerprint", "Public Key", "Signature Algorithm", "Key Usage", "Extended Key Usage"}}
+	for _, certInfo := range atRiskCertificates(certificates) {
+		rows = append(rows, append([]string{
+			certInfo.TargetName,
+			certInfo.APIVersion,
+			certInfo.Kind,
+			certInfo.Namespace,
+			certInfo.Name,
+			reportDataKey(certInfo),
+			certInfo.CommonName,
+			strconv.FormatBool(certInfo.IsCertificateAuthority),
+			certInfo.CertificateAuthorityCommonName,
+			reportExpirationDate(certInfo.NotAfter, certInfo.Expiration, time.RFC3339),
+			strconv.Itoa(certInfo.DaysRemaining),
+			strconv.FormatBool(certInfo.Expired),
+			strconv.FormatBool(certInfo.NotYetValid),
+			strconv.FormatBool(certInfo.Revoked),
+			joinDaysOut(certInfo.TriggeredDaysOut),
+			certInfo.Severity,
+			joinPolicyViolationMessages(certInfo.PolicyViolations),
+			certInfo.Owner,
+		}, detailColumns(certInfo.Details)...))
+	}
+	return rows
+}
+
+// atRiskKeystoreCertificates returns only the keystore certificates that have triggered a DaysOut threshold or failed a policy check
+func atRiskKeystoreCertificates(certificates []configv1.KeystoreInformation) []configv1.KeystoreInformation {
+	atRisk := []configv1.KeystoreInformation{}
+	for _, keystoreInfo := range certificates {
+		if isKeystoreCertificateAtRisk(keystoreInfo) {
+			atRisk = append(atRisk, keystoreInfo)
+		}
+	}
+	return atRisk
+}
+
+// keystoreReportRows returns the header and a row for each at-risk keystore certificate, used for the CSV attachment
+func keystoreReportRows(certificates []configv1.KeystoreInformation) [][]string {
+	rows := [][]string{{"APIVersion", "Kind", "Namespace", "Name", "Data Key", "Keystore Alias", "Certificate CN", "Is CA", "Signing CA CN", "Expiration Date", "Days Remaining", "Expired", "Not Yet Valid", "Triggered Days Out", "Severity", "Policy Violations", "Owner", "Subject", "Issuer", "Subject Alternative Names", "Serial Number", "SHA-256 Fingerprint", "Public Key", "Signature Algorithm", "Key Usage", "Extended Key Usage"}}
+	for _, keystoreInfo := range atRiskKeystoreCertificates(certificates) {
+		rows = append(rows, append([]string{
+			keystoreInfo.APIVersion,
+			keystoreInfo.Kind,
+			keystoreInfo.Namespace,
+			keystoreInfo.Name,
+			keystoreInfo.DataKey,
+			keystoreInfo.KeystoreAlias,
+			keystoreInfo.CommonName,
+			strconv.FormatBool(keystoreInfo.IsCertificateAuthority),
+			keystoreInfo.CertificateAuthorityCommonName,
+			reportExpirationDate(keystoreInfo.NotAfter, keystoreInfo.Expiration, time.RFC3339),
+			strconv.Itoa(keystoreInfo.DaysRemaining),
+			strconv.FormatBool(keystoreInfo.Expired),
+			strconv.FormatBool(keystoreInfo.NotYetValid),
+			joinDaysOut(keystoreInfo.TriggeredDaysOut),
+			keystoreInfo.Severity,
+			joinPolicyViolationMessages(keystoreInfo.PolicyViolations),
+			keystoreInfo.Owner,
+		}, detailColumns(keystoreInfo.Details)...))
+	}
+	return rows
+}
+
+// detailColumns returns the CSV columns for the certificate details, left empty when the target does not include them
+func detailColumns(details *configv1.CertificateDetails) []string {
+	if details == nil {
+		return make([]string, 9)
+	}
+	var sans []string
+	sans = append(sans, details.DNSNames...)
+	sans = append(sans, details.IPAddresses...)
+	sans = append(sans, details.URIs...)
+	sans = append(sans, details.EmailAddresses...)
+	publicKey := details.PublicKeyAlgorithm
+	if details.PublicKeySize > 0 {
+		publicKey += " " + strconv.Itoa(details.PublicKeySize)
+	}
+	return []string{
+		details.Subject,
+		details.Issuer,
+		strings.Join(sans, ", "),
+		details.SerialNumber,
+		details.FingerprintSHA256,
+		publicKey,
+		details.SignatureAlgorithm,
+		strings.Join(details.KeyUsage, ", "),
+		strings.Join(details.ExtendedKeyUsage, ", "),
+	}
+}
+
+// joinPolicyViolationRules formats the rules of policy violations as a comma separated list
+func joinPolicyViolationRules(violations []configv1.PolicyViolation) string {
+	var rules []string
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return strings.Join(rules, ", ")
+}
+
+// joinPolicyViolationMessages formats the rules and messages of policy violations as a semicolon separated list
+func joinPolicyViolationMessages(violations []configv1.PolicyViolation) string {
+	var messages []string
+	for _, violation := range violations {
+		messages = append(messages, violation.Rule+": "+violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// joinDaysOut formats a slice of triggered days out as a comma separated list
+func joinDaysOut(daysOut []int) string {
+	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(daysOut)), ", "), "[]")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+)
+
+func TestCreateReportAttachments(t *testing.T) {
+	g := NewWithT(t)
+	certificates := []configv1.CertificateInformation{
+		{
+			TargetName:       "secrets",
+			Kind:             "Secret",
+			Namespace:        "shop",
+			Name:             `checkout, "primary" tls`,
+			DataKey:          "tls.crt",
+			CommonName:       "checkout.example.com",
+			DaysRemaining:    12,
+			TriggeredDaysOut: []int{14, 30},
+			Severity:         "warning",
+			PolicyViolations: []configv1.PolicyViolation{{Rule: "weak-key", Severity: "critical", Message: `RSA key of 1024 bits, "2048" required`}},
+		},
+		{TargetName: "secrets", Kind: "Secret", Namespace: "shop", Name: "healthy-tls", DaysRemaining: 300},
+	}
+
+	attachments := createReportAttachments([]string{"csv", " JSON ", "xml"}, "certificates-at-risk", certificateReportRows(certificates), atRiskCertificates(certificates), lggr)
+	g.Expect(attachments).To(HaveLen(2))
+
+	// The CSV quotes the fields holding commas and quotes, and only holds the certificates at risk
+	g.Expect(attachments[0].Name).To(Equal("certificates-at-risk.csv"))
+	g.Expect(attachments[0].MimeType).To(Equal("text/csv"))
+	g.Expect(string(attachments[0].Data)).To(ContainSubstring(`"checkout, ""primary"" tls"`))
+	rows, err := csv.NewReader(bytes.NewReader(attachments[0].Data)).ReadAll()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rows).To(HaveLen(2))
+	g.Expect(rows[0][:6]).To(Equal([]string{"Target", "APIVersion", "Kind", "Namespace", "Name", "Data Key"}))
+	g.Expect(rows[1]).To(HaveLen(len(rows[0])))
+	g.Expect(rows[1][4]).To(Equal(`checkout, "primary" tls`))
+	g.Expect(rows[1]).To(ContainElement("14, 30"))
+	g.Expect(rows[1]).To(ContainElement(`weak-key: RSA key of 1024 bits, "2048" required`))
+
+	g.Expect(attachments[1].Name).To(Equal("certificates-at-risk.json"))
+	g.Expect(attachments[1].MimeType).To(Equal("application/json"))
+	g.Expect(json.Valid(attachments[1].Data)).To(BeTrue())
+	var decoded []configv1.CertificateInformation
+	g.Expect(json.Unmarshal(attachments[1].Data, &decoded)).To(Succeed())
+	g.Expect(decoded).To(HaveLen(1))
+	g.Expect(decoded[0].Name).To(Equal(`checkout, "primary" tls`))
+	g.Expect(decoded[0].PolicyViolations).To(Equal(certificates[0].PolicyViolations))
+}
+
+func TestCreateReportAttachmentsWithNothingAtRisk(t *testing.T) {
+	g := NewWithT(t)
+	certificates := []configv1.CertificateInformation{{Name: "healthy-tls", DaysRemaining: 300}}
+
+	attachments := createReportAttachments([]string{"csv", "json"}, "certificates-at-risk", certificateReportRows(certificates), atRiskCertificates(certificates), lggr)
+	g.Expect(attachments).To(HaveLen(2))
+	g.Expect(bytes.Count(attachments[0].Data, []byte("\n"))).To(Equal(1))
+	// An empty JSON array rather than null
+	g.Expect(string(attachments[1].Data)).To(Equal("[]"))
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/sha1"
 	"crypto/x509"
 	"encoding/hex"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/go-logr/logr"
 	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
 	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -120,44 +122,173 @@ func returnFilterType(labelFilter string) selection.Operator {
 }
 
 // SetupNamespaceSlice sets up the shared effectiveNamespaces from the provided YAML structures
-func SetupNamespaceSlice(namespaces []string, cl client.Client, lggr logr.Logger, serviceAccount string, targetNamespaceLabelSelector labels.Selector, scanningInterval int) ([]string, error) {
+// Namespaces are listed once and filtered with the include and exclude patterns, see helpers.FilterNamespaces for the pattern syntax
+func SetupNamespaceSlice(namespaces []string, excludeNamespaces []string, cl client.Client, lggr logr.Logger, serviceAccount string, targetNamespaceLabelSelector labels.Selector, scanningInterval int) ([]string, error) {
 
 	var effectiveNamespaces []string
+	var clusterNamespaces []string
 
-	// Loop through target namespaces
-	for _, elem := range namespaces {
-		namespaceList := &corev1.NamespaceList{}
-		ns := strings.TrimSpace(elem)
-		activeNamespace := ns
-		activeNamespaceDisplayName := ns
+	LogWithLevel("Querying for namespaces matching "+strings.Join(namespaces, ", ")+" with sa/"+serviceAccount, 3, lggr)
+	// Namespaces are cluster-scoped, so list them all and filter them here
+	namespaceList := &corev1.NamespaceList{}
+	namespaceListOptions := &client.ListOptions{LabelSelector: targetNamespaceLabelSelector}
+	err := cl.List(context.Background(), namespaceList, namespaceListOptions)
+	if err != nil {
+		lggr.Error(err, "Failed to list namespace in cluster!")
+		lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
+		time.Sleep(time.Second * time.Duration(scanningInterval))
+		return []string{}, err
+	}
+	for _, el := range namespaceList.Items {
+		clusterNamespaces = append(clusterNamespaces, el.Name)
+	}
 
-		if ns == "*" {
-			activeNamespace = ""
-			activeNamespaceDisplayName = "*"
-		}
+	filteredNamespaces, err := helpers.FilterNamespaces(clusterNamespaces, namespaces, excludeNamespaces)
+	if err != nil {
+		lggr.Error(err, "Failed to match namespace patterns!")
+		return []string{}, err
+	}
 
-		LogWithLevel("Querying for namespace/"+activeNamespaceDisplayName+" with sa/"+serviceAccount, 3, lggr)
-		// Get Namespace with the cached context
-		namespaceListOptions := &client.ListOptions{Namespace: activeNamespace, LabelSelector: targetNamespaceLabelSelector}
-		err := cl.List(context.Background(), namespaceList, namespaceListOptions)
-		if err != nil {
-			lggr.Error(err, "Failed to list namespace in cluster!")
-			lggr.Info("Running reconciler again in " + strconv.Itoa(scanningInterval) + "s")
-			time.Sleep(time.Second * time.Duration(scanningInterval))
-			return []string{}, err
-		}
-		// Loop through NamespaceList, create the effectiveNamespaces slice
-		for _, el := range namespaceList.Items {
-			if !defaults.ContainsString(effectiveNamespaces, el.Name) {
-				LogWithLevel("Adding ns/"+el.Name+" to scope", 3, lggr)
-				effectiveNamespaces = append(effectiveNamespaces, el.Name)
-			}
+	// Loop through the matched namespaces, create the effectiveNamespaces slice
+	for _, el := range filteredNamespaces {
+		if !defaults.ContainsString(effectiveNamespaces, el) {
+			LogWithLevel("Adding ns/"+el+" to scope", 3, lggr)
+			effectiveNamespaces = append(effectiveNamespaces, el)
 		}
 	}
 
 	return effectiveNamespaces, nil
 }
 
+// GetObjectAnnotations reads the scanning override annotations of an object, logging any that are invalid
+func GetObjectAnnotations(annotations map[string]string, objectRef string, lggr logr.Logger) helpers.ObjectAnnotations {
+	objectAnnotations, err := helpers.ParseObjectAnnotations(annotations)
+	if err != nil {
+		lggr.Error(err, "Failed to parse annotations on "+objectRef)
+	}
+	return objectAnnotations
+}
+
+// ObjectTimeOut returns the target TimeSlices, or the ones built from the object's days-out annotation when it is set
+func ObjectTimeOut(timeOut []configv1.TimeSlice, objectAnnotations helpers.ObjectAnnotations) []configv1.TimeSlice {
+	if len(objectAnnotations.DaysOut) > 0 {
+		return DaysOutToTimeOut(objectAnnotations.DaysOut)
+	}
+	return timeOut
+}
+
+// TargetSeverities returns the target severity thresholds, or the default ones when none are set
+func TargetSeverities(severities []configv1.SeverityThreshold) []configv1.SeverityThreshold {
+	if len(severities) > 0 {
+		return severities
+	}
+	defaultSeverities := []configv1.SeverityThreshold{}
+	for daysOut, severity := range defaults.Severities {
+		defaultSeverities = append(defaultSeverities, configv1.SeverityThreshold{DaysOut: daysOut, Severity: severity})
+	}
+	return defaultSeverities
+}
+
+// TargetPolicy returns a copy of the target policy with the defaults filled in, or nil when the target has no policy
+func TargetPolicy(policy *configv1.CertificatePolicy) *configv1.CertificatePolicy {
+	if policy == nil {
+		return nil
+	}
+	targetPolicy := *policy
+	if targetPolicy.MinimumRSAKeySize == 0 {
+		targetPolicy.MinimumRSAKeySize = defaults.PolicyMinimumRSAKeySize
+	}
+	return &targetPolicy
+}
+
+// CompileSentinelRules compiles the sentinel policy rules, logging and skipping any with an invalid expression
+func CompileSentinelRules(rules []configv1.PolicyRule, lggr logr.Logger) []helpers.PolicyRuleProgram {
+	programs, errs := helpers.CompilePolicyRules(rules)
+	for _, err := range errs {
+		lggr.Error(err, "Invalid policy rule expression, skipping it!")
+	}
+	return programs
+}
+
+// LoadTrustBundle returns the roots chains are verified against - nil for the system roots when no trust bundle ConfigMap is set
+func LoadTrustBundle(chainVerification configv1.ChainVerification, namespace string, clnt client.Client) (*x509.CertPool, error) {
+	if chainVerification.TrustBundle == "" {
+		return nil, nil
+	}
+	trustBundleKey := chainVerification.TrustBundleKey
+	if trustBundleKey == "" {
+		trustBundleKey = defaults.TrustBundleKey
+	}
+
+	trustBundle, err := GetConfigMap(chainVerification.TrustBundle, namespace, clnt)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := helpers.DecodeCertificateBytes([]byte(trustBundle.Data[trustBundleKey]), lggr)
+	if err != nil {
+		return nil, errors.New("no certificates found in configmap/" + chainVerification.TrustBundle + " key " + trustBundleKey)
+	}
+
+	roots := x509.NewCertPool()
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+	return roots, nil
+}
+
+// revocationCache holds the OCSP responses and CRLs across reconciles until their nextUpdate
+var revocationCache = helpers.NewRevocationCache()
+
+// NewRevocationChecker returns the revocation checker of a sentinel for a scan, or nil when revocation checking is not enabled
+func NewRevocationChecker(revocationCheck *configv1.RevocationCheck, lggr logr.Logger) *helpers.RevocationChecker {
+	if revocationCheck == nil || !revocationCheck.Enabled {
+		return nil
+	}
+	timeout := defaults.SetDefaultInt(defaults.RevocationCheckTimeout, revocationCheck.Timeout)
+	httpClient, err := helpers.NewRevocationHTTPClient(revocationCheck.HTTPProxy, time.Second*time.Duration(timeout))
+	if err != nil {
+		lggr.Error(err, "Invalid revocation check HTTP proxy, skipping revocation checks!")
+		return nil
+	}
+	scanTimeout := defaults.SetDefaultInt(defaults.RevocationScanTimeout, revocationCheck.ScanTimeout)
+	return &helpers.RevocationChecker{Client: httpClient, Cache: revocationCache, Deadline: time.Now().Add(time.Second * time.Duration(scanTimeout))}
+}
+
+// RevocationStatusFromResult converts the result of a revocation check into its .status form
+// The times are truncated to the seconds the API server stores so a rescan compares equal to the stored .status
+func RevocationStatusFromResult(result helpers.RevocationResult, err error) *configv1.RevocationStatus {
+	revocationStatus := &configv1.RevocationStatus{Status: result.Status, Source: result.Source, Responder: result.Responder, Reason: result.Reason}
+	if !result.RevokedAt.IsZero() {
+		revokedAt := metav1.NewTime(result.RevokedAt).Rfc3339Copy()
+		revocationStatus.RevokedAt = &revokedAt
+	}
+	if !result.NextUpdate.IsZero() {
+		nextUpdate := metav1.NewTime(result.NextUpdate).Rfc3339Copy()
+		revocationStatus.NextUpdate = &nextUpdate
+	}
+	if err != nil {
+		revocationStatus.Error = err.Error()
+	}
+	return revocationStatus
+}
+
+// NamespaceExclusions returns the namespace patterns to exclude, adding the system namespace preset when asked for
+func NamespaceExclusions(excludeNamespaces []string, excludeSystemNamespaces bool) []string {
+	exclusions := append([]string{}, excludeNamespaces...)
+	if excludeSystemNamespaces {
+		exclusions = append(exclusions, defaults.SystemNamespaces...)
+	}
+	return exclusions
+}
+
+// reportExpirationDate formats the NotAfter of a certificate for a report, falling back to the Expiration string of a status written before NotAfter was added
+func reportExpirationDate(notAfter metav1.Time, expiration string, layout string) string {
+	if notAfter.IsZero() {
+		return expiration
+	}
+	return notAfter.UTC().Format(layout)
+}
+
 // createUniqueCertificateChecksum takes a seedString and a certificate byte stream and creates a unique SHA1 hash to track
 func createUniqueCertificateChecksum(seedString string, cert *x509.Certificate) string {
 	// Hash the Certificate and add it to the string slice
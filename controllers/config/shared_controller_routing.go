@@ -0,0 +1,353 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reportRoute is a single CertificateSentinel SMTP report and the recipients it is sent to
+type reportRoute struct {
+	// To is the slice of recipients of this report
+	To []string
+	// CertificateSentinel is a copy of the CertificateSentinel with only the DiscoveredCertificates for these recipients
+	CertificateSentinel configv1.CertificateSentinel
+}
+
+// routeSMTPReport splits the DiscoveredCertificates of a CertificateSentinel into one report per recipient
+// Certificates without an owner, and every certificate when owner routing is not enabled, go to the SMTPDestinationEmailAddresses
+func routeSMTPReport(certificateSentinel configv1.CertificateSentinel, lggr logr.Logger, clnt client.Client) []reportRoute {
+	alertConfig := certificateSentinel.Spec.Alert.AlertConfiguration
+	fallbackRoute := reportRoute{To: alertConfig.SMTPDestinationEmailAddresses, CertificateSentinel: certificateSentinel}
+
+	if alertConfig.SMTPRouting != "owner" {
+		return []reportRoute{fallbackRoute}
+	}
+
+	namespaceContacts := make(map[string]string)
+	ownerCertificates := make(map[string][]configv1.CertificateInformation)
+	var fallbackCertificates []configv1.CertificateInformation
+
+	for _, certInfo := range certificateSentinel.Status.DiscoveredCertificates {
+		owners := resolveOwners(certInfo.Owner, certInfo.Namespace, alertConfig, namespaceContacts, lggr, clnt)
+		if len(owners) == 0 {
+			fallbackCertificates = append(fallbackCertificates, certInfo)
+			continue
+		}
+		for _, owner := range owners {
+			ownerCertificates[owner] = append(ownerCertificates[owner], certInfo)
+		}
+	}
+
+	// Sort the owners so reports go out in a stable order
+	var owners []string
+	for owner := range ownerCertificates {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	var routes []reportRoute
+	for _, owner := range owners {
+		if hasCertificatesAtRisk(ownerCertificates[owner]) {
+			routedSentinel := certificateSentinel
+			routedSentinel.Status.DiscoveredCertificates = ownerCertificates[owner]
+			routes = append(routes, reportRoute{To: []string{owner}, CertificateSentinel: routedSentinel})
+		}
+	}
+
+	if hasCertificatesAtRisk(fallbackCertificates) {
+		if len(fallbackRoute.To) == 0 {
+			LogWithLevel("No owner or smtp_destination_addresses found for some certificates at risk, they will not be reported", 1, lggr)
+		} else {
+			fallbackRoute.CertificateSentinel.Status.DiscoveredCertificates = fallbackCertificates
+			routes = append(routes, fallbackRoute)
+		}
+	}
+
+	return routes
+}
+
+// keystoreReportRoute is a single KeystoreSentinel SMTP report and the recipients it is sent to
+type keystoreReportRoute struct {
+	// To is the slice of recipients of this report
+	To []string
+	// KeystoreSentinel is a copy of the KeystoreSentinel with only the DiscoveredKeystoreCertificates for these recipients
+	KeystoreSentinel configv1.KeystoreSentinel
+}
+
+// routeKeystoreSMTPReport splits the DiscoveredKeystoreCertificates of a KeystoreSentinel into one report per recipient
+// Certificates without an owner, and every certificate when owner routing is not enabled, go to the SMTPDestinationEmailAddresses
+func routeKeystoreSMTPReport(keystoreSentinel configv1.KeystoreSentinel, lggr logr.Logger, clnt client.Client) []keystoreReportRoute {
+	alertConfig := keystoreSentinel.Spec.Alert.AlertConfiguration
+	fallbackRoute := keystoreReportRoute{To: alertConfig.SMTPDestinationEmailAddresses, KeystoreSentinel: keystoreSentinel}
+
+	if alertConfig.SMTPRouting != "owner" {
+		return []keystoreReportRoute{fallbackRoute}
+	}
+
+	namespaceContacts := make(map[string]string)
+	ownerCertificates := make(map[string][]configv1.KeystoreInformation)
+	var fallbackCertificates []configv1.KeystoreInformation
+
+	for _, keystoreInfo := range keystoreSentinel.Status.DiscoveredKeystoreCertificates {
+		owners := resolveOwners(keystoreInfo.Owner, keystoreInfo.Namespace, alertConfig, namespaceContacts, lggr, clnt)
+		if len(owners) == 0 {
+			fallbackCertificates = append(fallbackCertificates, keystoreInfo)
+			continue
+		}
+		for _, owner := range owners {
+			ownerCertificates[owner] = append(ownerCertificates[owner], keystoreInfo)
+		}
+	}
+
+	// Sort the owners so reports go out in a stable order
+	var owners []string
+	for owner := range ownerCertificates {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	var routes []keystoreReportRoute
+	for _, owner := range owners {
+		if hasKeystoreCertificatesAtRisk(ownerCertificates[owner]) {
+			routedSentinel := keystoreSentinel
+			routedSentinel.Status.DiscoveredKeystoreCertificates = ownerCertificates[owner]
+			routes = append(routes, keystoreReportRoute{To: []string{owner}, KeystoreSentinel: routedSentinel})
+		}
+	}
+
+	if hasKeystoreCertificatesAtRisk(fallbackCertificates) {
+		if len(fallbackRoute.To) == 0 {
+			LogWithLevel("No owner or smtp_destination_addresses found for some keystore certificates at risk, they will not be reported", 1, lggr)
+		} else {
+			fallbackRoute.KeystoreSentinel.Status.DiscoveredKeystoreCertificates = fallbackCertificates
+			routes = append(routes, fallbackRoute)
+		}
+	}
+
+	return routes
+}
+
+// deliverReportRoutes sends the report to each route of recipients not yet delivered in the report cycle, holding back failed routes until their retry backoff has passed
+// A delivered route is sent the next report once it falls due again, so a route that keeps failing does not hold back the reports of the other routes
+// It returns the deliveries of a cycle still in progress, or nil once every route is delivered, if any delivery was attempted, and the errors of the failed deliveries
+func deliverReportRoutes(recipients [][]string, deliveries []configv1.ReportDelivery, alertConfig configv1.AlertConfiguration, now time.Time, send func(route int) error, lggr logr.Logger) ([]configv1.ReportDelivery, bool, error) {
+	previous := map[string]configv1.ReportDelivery{}
+	for _, delivery := range deliveries {
+		previous[delivery.Recipients] = delivery
+	}
+
+	var updated []configv1.ReportDelivery
+	var deliveryErrors []string
+	attempted := false
+	complete := true
+	for i, to := range recipients {
+		delivery, ok := previous[strings.Join(to, ", ")]
+		if !ok {
+			delivery = configv1.ReportDelivery{Recipients: strings.Join(to, ", ")}
+		}
+		switch {
+		case delivery.Delivered != 0 && !scheduledReportDue(alertConfig, delivery.Delivered, now.Unix(), lggr):
+			// Already delivered in this report cycle
+		case delivery.NextAttempt > now.Unix():
+			LogWithLevel("Holding report to "+delivery.Recipients+" until "+time.Unix(delivery.NextAttempt, 0).UTC().String(), 2, lggr)
+			complete = false
+		default:
+			attempted = true
+			if err := send(i); err != nil {
+				lggr.Error(err, "Failed to deliver report to "+delivery.Recipients+"!")
+				delivery.Failures++
+				delivery.NextAttempt = now.Add(helpers.RetryBackoff(delivery.Failures, defaults.SMTPRetryBackoff, defaults.SMTPRetryBackoffMax)).Unix()
+				delivery.Error = err.Error()
+				deliveryErrors = append(deliveryErrors, err.Error())
+				complete = false
+			} else {
+				delivery.Delivered = now.Unix()
+				delivery.NextAttempt = 0
+				delivery.Error = ""
+			}
+		}
+		updated = append(updated, delivery)
+	}
+
+	if len(deliveryErrors) > 0 {
+		return updated, attempted, errors.New(strings.Join(deliveryErrors, "; "))
+	}
+	if !complete {
+		return updated, attempted, nil
+	}
+	return nil, attempted, nil
+}
+
+// resolveOwners returns the recipients for a discovered certificate, from its owner annotation or the owner label on its Namespace
+func resolveOwners(contact string, namespaceName string, alertConfig configv1.AlertConfiguration, namespaceContacts map[string]string, lggr logr.Logger, clnt client.Client) []string {
+	// Fall back to the contact label on the Namespace, looking each Namespace up only once
+	if contact == "" && alertConfig.SMTPOwnerNamespaceLabel != "" && namespaceName != "" {
+		namespaceContact, ok := namespaceContacts[namespaceName]
+		if !ok {
+			namespace := &corev1.Namespace{}
+			err := clnt.Get(context.Background(), client.ObjectKey{Name: namespaceName}, namespace)
+			if err != nil {
+				lggr.Error(err, "Failed to get namespace/"+namespaceName+" for owner routing")
+			}
+			namespaceContact = namespace.Labels[alertConfig.SMTPOwnerNamespaceLabel]
+			namespaceContacts[namespaceName] = namespaceContact
+		}
+		contact = namespaceContact
+	}
+
+	var owners []string
+	for _, owner := range strings.Split(contact, ",") {
+		owner = strings.TrimSpace(owner)
+		if owner == "" {
+			continue
+		}
+		if !strings.Contains(owner, "@") {
+			if alertConfig.SMTPOwnerDomain == "" {
+				LogWithLevel("Owner "+owner+" is not an email address and no smtp_owner_domain is set", 2, lggr)
+				continue
+			}
+			owner = owner + "@" + alertConfig.SMTPOwnerDomain
+		}
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// isCertificateAtRisk checks if a certificate has triggered a DaysOut threshold, expired, is not valid yet, failed a policy check, or been revoked
+func isCertificateAtRisk(certInfo configv1.CertificateInformation) bool {
+	return len(certInfo.TriggeredDaysOut) > 0 || certInfo.Expired || certInfo.NotYetValid || len(certInfo.PolicyViolations) > 0 || certInfo.Revoked
+}
+
+// isKeystoreCertificateAtRisk checks if a keystore certificate has triggered a DaysOut threshold, expired, is not valid yet, or failed a policy check
+func isKeystoreCertificateAtRisk(keystoreInfo configv1.KeystoreInformation) bool {
+	return len(keystoreInfo.TriggeredDaysOut) > 0 || keystoreInfo.Expired || keystoreInfo.NotYetValid || len(keystoreInfo.PolicyViolations) > 0
+}
+
+// hasCertificatesAtRisk checks if any of the certificates have triggered a DaysOut threshold, expired, are not valid yet, failed a policy check, or been revoked
+func hasCertificatesAtRisk(certificates []configv1.CertificateInformation) bool {
+	for _, certInfo := range certificates {
+		if isCertificateAtRisk(certInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeystoreCertificatesAtRisk checks if any of the keystore certificates have triggered a DaysOut threshold, expired, are not valid yet, or failed a policy check
+func hasKeystoreCertificatesAtRisk(certificates []configv1.KeystoreInformation) bool {
+	for _, keystoreInfo := range certificates {
+		if isKeystoreCertificateAtRisk(keystoreInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCertificatesBySeverity clears the at-risk state and policy violations of certificates below the minimum severity so they are left out of a report but still counted in its totals
+func filterCertificatesBySeverity(certificates []configv1.CertificateInformation, minimumSeverity string) []configv1.CertificateInformation {
+	filtered := []configv1.CertificateInformation{}
+	for _, certInfo := range certificates {
+		if !helpers.MeetsMinimumSeverity(certInfo.Severity, minimumSeverity) {
+			certInfo.TriggeredDaysOut = nil
+			certInfo.Expired = false
+			certInfo.NotYetValid = false
+		}
+		certInfo.PolicyViolations = filterPolicyViolationsBySeverity(certInfo.PolicyViolations, minimumSeverity)
+		filtered = append(filtered, certInfo)
+	}
+	return filtered
+}
+
+// filterKeystoreCertificatesBySeverity clears the at-risk state and policy violations of keystore certificates below the minimum severity so they are left out of a report but still counted in its totals
+func filterKeystoreCertificatesBySeverity(certificates []configv1.KeystoreInformation, minimumSeverity string) []configv1.KeystoreInformation {
+	filtered := []configv1.KeystoreInformation{}
+	for _, keystoreInfo := range certificates {
+		if !helpers.MeetsMinimumSeverity(keystoreInfo.Severity, minimumSeverity) {
+			keystoreInfo.TriggeredDaysOut = nil
+			keystoreInfo.Expired = false
+			keystoreInfo.NotYetValid = false
+		}
+		keystoreInfo.PolicyViolations = filterPolicyViolationsBySeverity(keystoreInfo.PolicyViolations, minimumSeverity)
+		filtered = append(filtered, keystoreInfo)
+	}
+	return filtered
+}
+
+// filterPolicyViolationsBySeverity returns only the policy violations at or above the minimum severity
+func filterPolicyViolationsBySeverity(violations []configv1.PolicyViolation, minimumSeverity string) []configv1.PolicyViolation {
+	var filtered []configv1.PolicyViolation
+	for _, violation := range violations {
+		if helpers.MeetsMinimumSeverity(violation.Severity, minimumSeverity) {
+			filtered = append(filtered, violation)
+		}
+	}
+	return filtered
+}
+
+// certificateUrgency ranks revoked certificates first, then the expired ones, then the not yet valid ones, then the rest
+func certificateUrgency(revoked bool, expired bool, notYetValid bool) int {
+	switch {
+	case revoked:
+		return 0
+	case expired:
+		return 1
+	case notYetValid:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// sortCertificatesByUrgency returns a copy of the certificates ordered by urgency and then by the fewest days remaining
+func sortCertificatesByUrgency(certificates []configv1.CertificateInformation) []configv1.CertificateInformation {
+	sorted := append([]configv1.CertificateInformation{}, certificates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		urgencyI := certificateUrgency(sorted[i].Revoked, sorted[i].Expired, sorted[i].NotYetValid)
+		urgencyJ := certificateUrgency(sorted[j].Revoked, sorted[j].Expired, sorted[j].NotYetValid)
+		if urgencyI != urgencyJ {
+			return urgencyI < urgencyJ
+		}
+		return sorted[i].DaysRemaining < sorted[j].DaysRemaining
+	})
+	return sorted
+}
+
+// sortKeystoreCertificatesByUrgency returns a copy of the keystore certificates ordered by urgency and then by the fewest days remaining
+func sortKeystoreCertificatesByUrgency(certificates []configv1.KeystoreInformation) []configv1.KeystoreInformation {
+	sorted := append([]configv1.KeystoreInformation{}, certificates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		urgencyI := certificateUrgency(false, sorted[i].Expired, sorted[i].NotYetValid)
+		urgencyJ := certificateUrgency(false, sorted[j].Expired, sorted[j].NotYetValid)
+		if urgencyI != urgencyJ {
+			return urgencyI < urgencyJ
+		}
+		return sorted[i].DaysRemaining < sorted[j].DaysRemaining
+	})
+	return sorted
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// These tests run without the envtest control plane, so they use plain Go tests with Gomega assertions rather than the Ginkgo suite
+
+func TestDeliverReportRoutesRetriesOnlyFailedRoutes(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+	recipients := [][]string{{"team-a@example.com"}, {"team-b@example.com"}}
+
+	// team-b fails on the first scan
+	var sent []int
+	deliveries, attempted, err := deliverReportRoutes(recipients, nil, configv1.AlertConfiguration{}, now, func(i int) error {
+		sent = append(sent, i)
+		if i == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, lggr)
+	g.Expect(err).To(MatchError("connection refused"))
+	g.Expect(attempted).To(BeTrue())
+	g.Expect(sent).To(Equal([]int{0, 1}))
+	g.Expect(deliveries).To(HaveLen(2))
+	g.Expect(deliveries[0].Delivered).To(Equal(now.Unix()))
+	g.Expect(deliveries[1].Failures).To(Equal(1))
+	g.Expect(deliveries[1].NextAttempt).To(BeNumerically(">", now.Unix()))
+
+	// Before the backoff passes nothing is sent
+	sent = nil
+	held, attempted, err := deliverReportRoutes(recipients, deliveries, configv1.AlertConfiguration{}, now.Add(time.Second), func(i int) error {
+		sent = append(sent, i)
+		return nil
+	}, lggr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempted).To(BeFalse())
+	g.Expect(sent).To(BeEmpty())
+	g.Expect(held).To(Equal(deliveries))
+
+	// Once it passes only team-b is retried, and the delivered cycle is cleared
+	sent = nil
+	deliveries, attempted, err = deliverReportRoutes(recipients, deliveries, configv1.AlertConfiguration{}, now.Add(time.Hour), func(i int) error {
+		sent = append(sent, i)
+		return nil
+	}, lggr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempted).To(BeTrue())
+	g.Expect(sent).To(Equal([]int{1}))
+	g.Expect(deliveries).To(BeNil())
+}
+
+func TestDeliverReportRoutesStartsNextCycleDespiteFailingRoute(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+	alertConfig := configv1.AlertConfiguration{ReportInterval: "daily"}
+	recipients := [][]string{{"team-a@example.com"}, {"typo@example"}}
+
+	// The mistyped owner address never accepts the report
+	var sent []int
+	send := func(i int) error {
+		sent = append(sent, i)
+		if i == 1 {
+			return errors.New("550 no such user")
+		}
+		return nil
+	}
+
+	deliveries, _, err := deliverReportRoutes(recipients, nil, alertConfig, now, send, lggr)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(sent).To(Equal([]int{0, 1}))
+
+	// Within the report interval only the failing route is retried
+	sent = nil
+	deliveries, _, err = deliverReportRoutes(recipients, deliveries, alertConfig, now.Add(2*time.Hour), send, lggr)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(sent).To(Equal([]int{1}))
+
+	// Once the next report falls due team-a is sent it, while the failing route is still retried
+	sent = nil
+	nextReport := now.Add(25 * time.Hour)
+	deliveries, attempted, err := deliverReportRoutes(recipients, deliveries, alertConfig, nextReport, send, lggr)
+	g.Expect(err).To(MatchError("550 no such user"))
+	g.Expect(attempted).To(BeTrue())
+	g.Expect(sent).To(Equal([]int{0, 1}))
+	g.Expect(deliveries[0].Delivered).To(Equal(nextReport.Unix()))
+	g.Expect(deliveries[1].Delivered).To(BeZero())
+	g.Expect(deliveries[1].Failures).To(Equal(3))
+}
+
+func TestDeliverReportRoutesBacksOff(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+	deliveries := []configv1.ReportDelivery{{Recipients: "team-a@example.com", Failures: 3, NextAttempt: now.Unix()}}
+
+	deliveries, _, err := deliverReportRoutes([][]string{{"team-a@example.com"}}, deliveries, configv1.AlertConfiguration{}, now, func(i int) error {
+		return errors.New("connection refused")
+	}, lggr)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(deliveries[0].Failures).To(Equal(4))
+	g.Expect(deliveries[0].NextAttempt).To(Equal(now.Add(8 * time.Minute).Unix()))
+	g.Expect(deliveries[0].Error).To(Equal("connection refused"))
+}
+
+func TestCertificatesAtRiskIncludeExpiredAndNotYetValid(t *testing.T) {
+	g := NewWithT(t)
+
+	// Neither certificate has triggered a daysOut threshold, ie the target has no daysOut configured
+	expired := configv1.CertificateInformation{Name: "expired-tls", Expired: true, Severity: "expired"}
+	notYetValid := configv1.CertificateInformation{Name: "future-tls", NotYetValid: true}
+	g.Expect(isCertificateAtRisk(expired)).To(BeTrue())
+	g.Expect(isCertificateAtRisk(notYetValid)).To(BeTrue())
+	g.Expect(isCertificateAtRisk(configv1.CertificateInformation{Name: "valid-tls"})).To(BeFalse())
+	g.Expect(hasCertificatesAtRisk([]configv1.CertificateInformation{{Name: "valid-tls"}, notYetValid})).To(BeTrue())
+
+	g.Expect(isKeystoreCertificateAtRisk(configv1.KeystoreInformation{Name: "expired-jks", Expired: true})).To(BeTrue())
+	g.Expect(isKeystoreCertificateAtRisk(configv1.KeystoreInformation{Name: "future-jks", NotYetValid: true})).To(BeTrue())
+	g.Expect(hasKeystoreCertificatesAtRisk([]configv1.KeystoreInformation{{Name: "valid-jks"}})).To(BeFalse())
+
+	// Expired certificates stay in a report filtered by severity, certificates below it are left out
+	filtered := filterCertificatesBySeverity([]configv1.CertificateInformation{expired, notYetValid}, "critical")
+	g.Expect(isCertificateAtRisk(filtered[0])).To(BeTrue())
+	g.Expect(isCertificateAtRisk(filtered[1])).To(BeFalse())
+}
+
+func TestResolveOwners(t *testing.T) {
+	clnt := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"contact": "team-a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	).Build()
+	alertConfig := configv1.AlertConfiguration{SMTPRouting: "owner", SMTPOwnerNamespaceLabel: "contact", SMTPOwnerDomain: "example.com"}
+
+	tests := []struct {
+		name        string
+		contact     string
+		namespace   string
+		alertConfig configv1.AlertConfiguration
+		expected    []string
+	}{
+		{name: "splits and trims a list of owners", contact: " dev@example.com , ops@example.org", namespace: "team-a", alertConfig: alertConfig, expected: []string{"dev@example.com", "ops@example.org"}},
+		{name: "affixes the owner domain to bare names", contact: "dev,ops@example.org", namespace: "team-a", alertConfig: alertConfig, expected: []string{"dev@example.com", "ops@example.org"}},
+		{name: "drops bare names without an owner domain", contact: "dev,ops@example.org", namespace: "team-a", alertConfig: configv1.AlertConfiguration{SMTPRouting: "owner"}, expected: []string{"ops@example.org"}},
+		{name: "falls back to the namespace label", namespace: "team-a", alertConfig: alertConfig, expected: []string{"team-a@example.com"}},
+		{name: "has no owner without the namespace label", namespace: "unlabeled", alertConfig: alertConfig},
+		{name: "has no owner when the namespace does not exist", namespace: "missing", alertConfig: alertConfig},
+		{name: "has no owner for cluster-scoped objects", alertConfig: alertConfig},
+		{name: "ignores the namespace label unless it is configured", namespace: "team-a", alertConfig: configv1.AlertConfiguration{SMTPRouting: "owner", SMTPOwnerDomain: "example.com"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(resolveOwners(test.contact, test.namespace, test.alertConfig, map[string]string{}, lggr, clnt)).To(Equal(test.expected))
+		})
+	}
+}
+
+func TestResolveOwnersLooksUpEachNamespaceOnce(t *testing.T) {
+	g := NewWithT(t)
+	clnt := fake.NewClientBuilder().WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"contact": "team-a"}}}).Build()
+	alertConfig := configv1.AlertConfiguration{SMTPRouting: "owner", SMTPOwnerNamespaceLabel: "contact", SMTPOwnerDomain: "example.com"}
+
+	namespaceContacts := map[string]string{}
+	g.Expect(resolveOwners("", "team-a", alertConfig, namespaceContacts, lggr, clnt)).To(Equal([]string{"team-a@example.com"}))
+	g.Expect(namespaceContacts).To(Equal(map[string]string{"team-a": "team-a"}))
+
+	// The cached contact is used even once the label is gone
+	namespace := &corev1.Namespace{}
+	g.Expect(clnt.Get(context.Background(), client.ObjectKey{Name: "team-a"}, namespace)).To(Succeed())
+	namespace.Labels = nil
+	g.Expect(clnt.Update(context.Background(), namespace)).To(Succeed())
+	g.Expect(resolveOwners("", "team-a", alertConfig, namespaceContacts, lggr, clnt)).To(Equal([]string{"team-a@example.com"}))
+}
+
+// routedCertificates lists the recipients of each route with the names of the certificates it reports
+func routedCertificates(routes []reportRoute) map[string][]string {
+	routed := map[string][]string{}
+	for _, route := range routes {
+		var names []string
+		for _, certInfo := range route.CertificateSentinel.Status.DiscoveredCertificates {
+			names = append(names, certInfo.Name)
+		}
+		routed[strings.Join(route.To, ", ")] = names
+	}
+	return routed
+}
+
+func TestRouteSMTPReport(t *testing.T) {
+	clnt := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform", Labels: map[string]string{"contact": "platform"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+	).Build()
+	discovered := []configv1.CertificateInformation{
+		{Name: "shared-tls", Namespace: "apps", Owner: "team-a, team-b@example.org", TriggeredDaysOut: []int{30}},
+		{Name: "ingress-tls", Namespace: "platform", TriggeredDaysOut: []int{30}},
+		{Name: "orphan-tls", Namespace: "apps", Expired: true},
+		{Name: "healthy-tls", Namespace: "apps", Owner: "team-c"},
+	}
+	sentinel := func(alertConfig configv1.AlertConfiguration) configv1.CertificateSentinel {
+		certificateSentinel := configv1.CertificateSentinel{}
+		certificateSentinel.Spec.Alert.AlertConfiguration = alertConfig
+		certificateSentinel.Status.DiscoveredCertificates = discovered
+		return certificateSentinel
+	}
+
+	t.Run("sends every certificate to the destination addresses without owner routing", func(t *testing.T) {
+		g := NewWithT(t)
+		routes := routeSMTPReport(sentinel(configv1.AlertConfiguration{SMTPDestinationEmailAddresses: []string{"platform-team@example.com"}}), lggr, clnt)
+		g.Expect(routedCertificates(routes)).To(Equal(map[string][]string{
+			"platform-team@example.com": {"shared-tls", "ingress-tls", "orphan-tls", "healthy-tls"},
+		}))
+	})
+
+	t.Run("splits the report per owner with unowned certificates sent to the fallback recipients", func(t *testing.T) {
+		g := NewWithT(t)
+		routes := routeSMTPReport(sentinel(configv1.AlertConfiguration{
+			SMTPRouting:                   "owner",
+			SMTPOwnerNamespaceLabel:       "contact",
+			SMTPOwnerDomain:               "example.com",
+			SMTPDestinationEmailAddresses: []string{"platform-team@example.com"},
+		}), lggr, clnt)
+		// team-c only owns a healthy certificate, so it is not sent a report
+		g.Expect(routedCertificates(routes)).To(Equal(map[string][]string{
+			"platform@example.com":      {"ingress-tls"},
+			"team-a@example.com":        {"shared-tls"},
+			"team-b@example.org":        {"shared-tls"},
+			"platform-team@example.com": {"orphan-tls"},
+		}))
+		g.Expect(routes[len(routes)-1].To).To(Equal([]string{"platform-team@example.com"}))
+	})
+
+	t.Run("leaves unowned certificates out without fallback recipients", func(t *testing.T) {
+		g := NewWithT(t)
+		routes := routeSMTPReport(sentinel(configv1.AlertConfiguration{SMTPRouting: "owner", SMTPOwnerNamespaceLabel: "contact", SMTPOwnerDomain: "example.com"}), lggr, clnt)
+		g.Expect(routedCertificates(routes)).To(Equal(map[string][]string{
+			"platform@example.com": {"ingress-tls"},
+			"team-a@example.com":   {"shared-tls"},
+			"team-b@example.org":   {"shared-tls"},
+		}))
+	})
+}
+
+func TestSortCertificatesByUrgency(t *testing.T) {
+	g := NewWithT(t)
+	certificates := []configv1.CertificateInformation{
+		{Name: "expiring-30", DaysRemaining: 30, TriggeredDaysOut: []int{30}},
+		{Name: "future", NotYetValid: true, DaysRemaining: 400},
+		{Name: "expiring-7-a", DaysRemaining: 7, TriggeredDaysOut: []int{7, 30}},
+		{Name: "expired-yesterday", Expired: true, DaysRemaining: -1},
+		{Name: "revoked", Revoked: true, DaysRemaining: 200},
+		{Name: "expiring-7-b", DaysRemaining: 7, TriggeredDaysOut: []int{7, 30}},
+		{Name: "expired-last-month", Expired: true, DaysRemaining: -30},
+	}
+
+	var names []string
+	for _, certInfo := range sortCertificatesByUrgency(certificates) {
+		names = append(names, certInfo.Name)
+	}
+	// Revoked, expired, and not yet valid certificates come first, then the fewest days remaining, with ties kept in their scanned order
+	g.Expect(names).To(Equal([]string{"revoked", "expired-last-month", "expired-yesterday", "future", "expiring-7-a", "expiring-7-b", "expiring-30"}))
+	g.Expect(certificates[0].Name).To(Equal("expiring-30"))
+
+	keystoreCertificates := sortKeystoreCertificatesByUrgency([]configv1.KeystoreInformation{
+		{KeystoreAlias: "expiring", DaysRemaining: 10},
+		{KeystoreAlias: "future", NotYetValid: true, DaysRemaining: 100},
+		{KeystoreAlias: "expired", Expired: true, DaysRemaining: -3},
+	})
+	g.Expect(keystoreCertificates[0].KeystoreAlias).To(Equal("expired"))
+	g.Expect(keystoreCertificates[1].KeystoreAlias).To(Equal("future"))
+	g.Expect(keystoreCertificates[2].KeystoreAlias).To(Equal("expiring"))
+}
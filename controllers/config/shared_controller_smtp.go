@@ -0,0 +1,100 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+	helpers "github.com/kenmoini/certificate-sentinel-operator/controllers/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newSMTPServerConfig assembles the SMTP server connection, authentication, and TLS options from an AlertConfiguration and its Secrets in the given Namespace
+func newSMTPServerConfig(alertConfig configv1.AlertConfiguration, namespace string, clnt client.Client) (helpers.SMTPServerConfig, error) {
+	serverConfig := helpers.SMTPServerConfig{
+		Endpoint:      alertConfig.SMTPEndpoint,
+		AuthType:      alertConfig.SMTPAuthType,
+		UseTLS:        defaults.SetDefaultBool(&defaults.SMTPAuthUseSSL, alertConfig.SMTPAuthUseSSL),
+		UseSTARTTLS:   defaults.SetDefaultBool(&defaults.SMTPAuthUseSTARTTLS, alertConfig.SMTPAuthUseSTARTTLS),
+		TLSServerName: alertConfig.SMTPTLSServerName,
+		TLSMinVersion: alertConfig.SMTPTLSMinVersion,
+	}
+
+	// Get SMTP Authentication Secret if the AuthType is not `none`
+	smtpAuthSecret := &corev1.Secret{}
+	if alertConfig.SMTPAuthType != "none" {
+		smtpAuthSecret, _ = GetSecret(alertConfig.SMTPAuthSecretName, namespace, clnt)
+	}
+
+	// Assign SMTP Auth vars where needed
+	if len(smtpAuthSecret.Data) > 0 {
+		serverConfig.Username = string(smtpAuthSecret.Data["username"])
+		serverConfig.Password = string(smtpAuthSecret.Data["password"])
+		serverConfig.Identity = string(smtpAuthSecret.Data["identity"])
+		serverConfig.CramSecret = string(smtpAuthSecret.Data["cram"])
+		serverConfig.OAuth2ClientID = string(smtpAuthSecret.Data["client_id"])
+		serverConfig.OAuth2ClientSecret = string(smtpAuthSecret.Data["client_secret"])
+		serverConfig.OAuth2TokenURL = string(smtpAuthSecret.Data["token_url"])
+		serverConfig.OAuth2Scopes = strings.FieldsFunc(string(smtpAuthSecret.Data["scopes"]), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	}
+
+	// Get the SMTP TLS Secret with the CA bundle and client certificate if one is referenced
+	if alertConfig.SMTPTLSSecretName != "" {
+		smtpTLSSecret, err := GetSecret(alertConfig.SMTPTLSSecretName, namespace, clnt)
+		if err != nil {
+			return serverConfig, err
+		}
+		serverConfig.TLSCABundle = smtpTLSSecret.Data["ca.crt"]
+		serverConfig.TLSClientCertificate = smtpTLSSecret.Data[corev1.TLSCertKey]
+		serverConfig.TLSClientKey = smtpTLSSecret.Data[corev1.TLSPrivateKeyKey]
+	}
+
+	// Get the DKIM signing key if one is referenced, refusing to send unsigned mail when DKIM is only partly configured
+	if alertConfig.SMTPDKIMSecretName == "" && (alertConfig.SMTPDKIMSelector != "" || alertConfig.SMTPDKIMDomain != "") {
+		return serverConfig, errors.New("smtp_dkim_secret is required when smtp_dkim_selector or smtp_dkim_domain is set")
+	}
+	if alertConfig.SMTPDKIMSecretName != "" {
+		smtpDKIMSecret, err := GetSecret(alertConfig.SMTPDKIMSecretName, namespace, clnt)
+		if err != nil {
+			return serverConfig, err
+		}
+		serverConfig.DKIMPrivateKey = smtpDKIMSecret.Data["private_key"]
+		serverConfig.DKIMSelector = alertConfig.SMTPDKIMSelector
+		serverConfig.DKIMDomain = alertConfig.SMTPDKIMDomain
+		if serverConfig.DKIMDomain == "" && strings.Contains(alertConfig.SMTPSenderEmailAddress, "@") {
+			serverConfig.DKIMDomain = alertConfig.SMTPSenderEmailAddress[strings.LastIndex(alertConfig.SMTPSenderEmailAddress, "@")+1:]
+		}
+		if len(serverConfig.DKIMPrivateKey) == 0 {
+			return serverConfig, errors.New("no private_key found in DKIM secret/" + alertConfig.SMTPDKIMSecretName)
+		}
+		if serverConfig.DKIMSelector == "" {
+			return serverConfig, errors.New("smtp_dkim_selector is required when smtp_dkim_secret is set")
+		}
+		if serverConfig.DKIMDomain == "" {
+			return serverConfig, errors.New("smtp_dkim_domain is required when smtp_sender_address has no domain")
+		}
+	}
+
+	return serverConfig, nil
+}
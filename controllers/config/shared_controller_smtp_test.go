@@ -0,0 +1,114 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewSMTPServerConfigDKIM(t *testing.T) {
+	dkimSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "sentinel"}, Data: data}
+	}
+	clnt := fake.NewClientBuilder().WithObjects(
+		dkimSecret("dkim", map[string][]byte{"private_key": []byte("key")}),
+		dkimSecret("dkim-no-key", map[string][]byte{"selector": []byte("sentinel")}),
+	).Build()
+
+	tests := []struct {
+		name        string
+		alertConfig configv1.AlertConfiguration
+		wantErr     string
+		wantDomain  string
+	}{
+		{
+			name:        "complete",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMSecretName: "dkim", SMTPDKIMSelector: "sentinel"},
+			wantDomain:  "example.com",
+		},
+		{
+			name:        "explicit domain",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel", SMTPDKIMSecretName: "dkim", SMTPDKIMSelector: "sentinel", SMTPDKIMDomain: "example.org"},
+			wantDomain:  "example.org",
+		},
+		{
+			name:        "missing selector",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMSecretName: "dkim"},
+			wantErr:     "smtp_dkim_selector is required",
+		},
+		{
+			name:        "missing private key",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMSecretName: "dkim-no-key", SMTPDKIMSelector: "sentinel"},
+			wantErr:     "no private_key found in DKIM secret/dkim-no-key",
+		},
+		{
+			name:        "missing domain",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel", SMTPDKIMSecretName: "dkim", SMTPDKIMSelector: "sentinel"},
+			wantErr:     "smtp_dkim_domain is required",
+		},
+		{
+			name:        "missing secret",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMSelector: "sentinel"},
+			wantErr:     "smtp_dkim_secret is required",
+		},
+		{
+			name:        "secret not found",
+			alertConfig: configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMSecretName: "missing", SMTPDKIMSelector: "sentinel"},
+			wantErr:     "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			serverConfig, err := newSMTPServerConfig(tt.alertConfig, "sentinel", clnt)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(serverConfig.DKIMPrivateKey).To(Equal([]byte("key")))
+			g.Expect(serverConfig.DKIMSelector).To(Equal("sentinel"))
+			g.Expect(serverConfig.DKIMDomain).To(Equal(tt.wantDomain))
+		})
+	}
+}
+
+func TestIncompleteDKIMConfigurationFailsDelivery(t *testing.T) {
+	g := NewWithT(t)
+	clnt := fake.NewClientBuilder().Build()
+	alertConfig := configv1.AlertConfiguration{SMTPAuthType: "none", SMTPSenderEmailAddress: "sentinel@example.com", SMTPDKIMDomain: "example.com"}
+
+	deliveries, attempted, err := deliverReportRoutes([][]string{{"team-a@example.com"}}, nil, alertConfig, time.Now(), func(i int) error {
+		_, err := newSMTPServerConfig(alertConfig, "sentinel", clnt)
+		return err
+	}, lggr)
+
+	// The controller records this error as the lastReportError
+	g.Expect(attempted).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring("smtp_dkim_secret is required")))
+	g.Expect(deliveries).To(HaveLen(1))
+	g.Expect(deliveries[0].Error).To(ContainSubstring("smtp_dkim_secret is required"))
+	g.Expect(deliveries[0].Delivered).To(BeZero())
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReportTemplates holds the templates a report is rendered with, either the built in layouts or overrides from a ConfigMap
+type ReportTemplates struct {
+	// Subject is the email subject, rendered with the ReportSubjectStructure
+	Subject string
+	// Text is the plain text report used in logger reports and text SMTP reports
+	Text string
+	// TextHeader is the header and footer row of the plain text report
+	TextHeader string
+	// TextRow is a single certificate row of the plain text report
+	TextRow string
+	// HTML is the HTML report used in SMTP reports
+	HTML string
+	// HTMLHeader is the header and footer row of the HTML report
+	HTMLHeader string
+	// HTMLRow is a single certificate row of the HTML report
+	HTMLRow string
+}
+
+// ReportSubjectStructure provides the struct for the Subject template
+type ReportSubjectStructure struct {
+	Namespace          string
+	Name               string
+	AlertName          string
+	ClusterName        string
+	ClusterAPIEndpoint string
+	TotalCerts         string
+	ExpiringCerts      string
+	// RevokedCerts is the number of revoked certificates in the report
+	RevokedCerts string
+	// PolicyViolations is the number of policy violations in the report
+	PolicyViolations string
+	// MostUrgentDaysOut is the lowest triggered days out threshold in the report, or 0 when there are none, kept as an int for comparisons such as `{{ if le .MostUrgentDaysOut 7 }}`
+	MostUrgentDaysOut int
+	// Severity is the most urgent severity in the report, ie `critical`
+	Severity string
+}
+
+// Keys of the report template overrides in the ConfigMap referenced by `.spec.alert.reportTemplates`
+const (
+	ReportTemplateSubjectKey    = "subject"
+	ReportTemplateTextKey       = "text"
+	ReportTemplateTextHeaderKey = "text_header"
+	ReportTemplateTextRowKey    = "text_row"
+	ReportTemplateHTMLKey       = "html"
+	ReportTemplateHTMLHeaderKey = "html_header"
+	ReportTemplateHTMLRowKey    = "html_row"
+)
+
+// SMTPReportSubject is the default subject of the emailed CertificateSentinel reports
+const SMTPReportSubject = "Certificate Sentinel Operator - CertificateSentinel Report"
+
+// SMTPKeystoreReportSubject is the default subject of the emailed KeystoreSentinel reports
+const SMTPKeystoreReportSubject = "Certificate Sentinel Operator - KeystoreSentinel Report"
+
+// certificateReportTemplates returns the built in CertificateSentinel report layouts
+func certificateReportTemplates() ReportTemplates {
+	return ReportTemplates{
+		Subject:    SMTPReportSubject,
+		Text:       LoggerReport,
+		TextHeader: LoggerReportHeader,
+		TextRow:    LoggerReportLine,
+		HTML:       HTMLSMTPReportBody,
+		HTMLHeader: HTMLSMTPReportHeader,
+		HTMLRow:    HTMLSMTPReportLine,
+	}
+}
+
+// keystoreReportTemplates returns the built in KeystoreSentinel report layouts
+func keystoreReportTemplates() ReportTemplates {
+	return ReportTemplates{
+		Subject:    SMTPKeystoreReportSubject,
+		Text:       LoggerKeystoreReport,
+		TextHeader: LoggerKeystoreReportHeader,
+		TextRow:    LoggerKeystoreReportLine,
+		HTML:       HTMLSMTPKeystoreReportBody,
+		HTMLHeader: HTMLSMTPKeystoreReportHeader,
+		HTMLRow:    HTMLSMTPKeystoreReportLine,
+	}
+}
+
+// loadReportTemplates overrides the default report templates with the keys set in the alert's ConfigMap, and then the alert's `smtp_subject`
+// Overrides that fail to parse are logged and the default template is kept so a report is still sent
+func loadReportTemplates(reportTemplates ReportTemplates, alert configv1.Alert, namespace string, lggr logr.Logger, clnt client.Client) ReportTemplates {
+	if alert.ReportTemplates != "" {
+		reportTemplates = loadReportTemplatesConfigMap(reportTemplates, alert.ReportTemplates, namespace, lggr, clnt)
+	}
+
+	if alert.AlertConfiguration.SMTPSubject != "" {
+		if err := parseReportTemplate(ReportTemplateSubjectKey, alert.AlertConfiguration.SMTPSubject); err != nil {
+			lggr.Error(err, "Error parsing the smtp_subject template, using the default subject!")
+		} else {
+			reportTemplates.Subject = alert.AlertConfiguration.SMTPSubject
+		}
+	}
+
+	return reportTemplates
+}
+
+// loadReportTemplatesConfigMap overrides the report templates with the keys set in a ConfigMap
+func loadReportTemplatesConfigMap(reportTemplates ReportTemplates, configMapName string, namespace string, lggr logr.Logger, clnt client.Client) ReportTemplates {
+	templatesConfigMap, err := GetConfigMap(configMapName, namespace, clnt)
+	if err != nil {
+		lggr.Error(err, "Failed to get report templates configmap/"+configMapName+", using the default templates!")
+		return reportTemplates
+	}
+
+	overrides := map[string]*string{
+		ReportTemplateSubjectKey:    &reportTemplates.Subject,
+		ReportTemplateTextKey:       &reportTemplates.Text,
+		ReportTemplateTextHeaderKey: &reportTemplates.TextHeader,
+		ReportTemplateTextRowKey:    &reportTemplates.TextRow,
+		ReportTemplateHTMLKey:       &reportTemplates.HTML,
+		ReportTemplateHTMLHeaderKey: &reportTemplates.HTMLHeader,
+		ReportTemplateHTMLRowKey:    &reportTemplates.HTMLRow,
+	}
+	for key, reportTemplate := range overrides {
+		override, ok := templatesConfigMap.Data[key]
+		if !ok {
+			continue
+		}
+		if err := parseReportTemplate(key, override); err != nil {
+			lggr.Error(err, "Error parsing the "+key+" template in configmap/"+configMapName+", using the default template!")
+			continue
+		}
+		*reportTemplate = override
+	}
+
+	return reportTemplates
+}
+
+// parseReportTemplate checks a report template parses, using html/template for the HTML templates so their values are escaped
+// The subject is also rendered once so a reference to a field the ReportSubjectStructure does not have is caught before a report is sent
+func parseReportTemplate(key string, reportTemplate string) error {
+	switch key {
+	case ReportTemplateHTMLKey, ReportTemplateHTMLHeaderKey, ReportTemplateHTMLRowKey:
+		_, err := htmltemplate.New(key).Parse(reportTemplate)
+		return err
+	case ReportTemplateSubjectKey:
+		tmpl, err := template.New(key).Parse(reportTemplate)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(io.Discard, ReportSubjectStructure{})
+	}
+	_, err := template.New(key).Parse(reportTemplate)
+	return err
+}
+
+// renderReportSubject renders the Subject template onto a single line
+func renderReportSubject(subjectTemplate string, subjectStructure ReportSubjectStructure, lggr logr.Logger) string {
+	tmpl, err := template.New("subject").Parse(subjectTemplate)
+	if err != nil {
+		lggr.Error(err, "Error parsing subject template!")
+		return subjectTemplate
+	}
+	subjectBuf := new(strings.Builder)
+	if err = tmpl.Execute(subjectBuf, subjectStructure); err != nil {
+		lggr.Error(err, "Error executing subject template!")
+	}
+	// Subjects are a single header line
+	return strings.Join(strings.Fields(subjectBuf.String()), " ")
+}
+
+// reportClusterName returns the cluster name shown in reports, either the alert's `clusterName` or the API server hostname without an `api.` prefix
+func reportClusterName(alertConfig configv1.AlertConfiguration, clusterEndpoint string) string {
+	if alertConfig.ClusterName != "" {
+		return alertConfig.ClusterName
+	}
+	if endpoint, err := url.Parse(clusterEndpoint); err == nil && endpoint.Hostname() != "" {
+		return strings.TrimPrefix(endpoint.Hostname(), "api.")
+	}
+	return clusterEndpoint
+}
+
+// mostUrgentDaysOut returns the lowest triggered days out threshold across a set of triggered days out lists, or 0 when none were triggered
+func mostUrgentDaysOut(triggeredDaysOut [][]int) int {
+	mostUrgent := 0
+	for _, daysOut := range triggeredDaysOut {
+		for _, days := range daysOut {
+			if mostUrgent == 0 || days < mostUrgent {
+				mostUrgent = days
+			}
+		}
+	}
+	return mostUrgent
+}
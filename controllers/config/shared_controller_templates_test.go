@@ -0,0 +1,182 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"testing"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHTMLReportRowsEscapeValues(t *testing.T) {
+	g := NewWithT(t)
+
+	tmpl, err := htmltemplate.New("tableLine").Parse(HTMLSMTPReportLine)
+	g.Expect(err).NotTo(HaveOccurred())
+	lineBuf := new(bytes.Buffer)
+	g.Expect(tmpl.Execute(lineBuf, HTMLReportLineStructure{
+		Name:       "app-tls",
+		CommonName: "<script>alert(1)</script>.example.com",
+		RowStyles:  htmltemplate.CSS("background:#FFF;"),
+		CellStyles: htmltemplate.CSS("padding:6px;text-align:left;"),
+	})).To(Succeed())
+
+	g.Expect(lineBuf.String()).To(ContainSubstring("&lt;script&gt;alert(1)&lt;/script&gt;.example.com"))
+	g.Expect(lineBuf.String()).NotTo(ContainSubstring("<script>"))
+	g.Expect(lineBuf.String()).To(ContainSubstring(`<tr style="background:#FFF;"><td style="padding:6px;text-align:left;">`))
+}
+
+func TestParseReportTemplate(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(parseReportTemplate(ReportTemplateTextRowKey, "{{ .Name }}")).To(Succeed())
+	g.Expect(parseReportTemplate(ReportTemplateHTMLRowKey, "<tr><td>{{ .Name }}</td></tr>")).To(Succeed())
+	g.Expect(parseReportTemplate(ReportTemplateHTMLRowKey, "<tr><td>{{ .Name ")).NotTo(Succeed())
+}
+
+func TestRenderReportSubject(t *testing.T) {
+	subjectStructure := ReportSubjectStructure{
+		Namespace:         "sentinel",
+		Name:              "cluster-certificates",
+		AlertName:         "ops-team",
+		ClusterName:       "ocp.example.com",
+		TotalCerts:        "12",
+		ExpiringCerts:     "3",
+		MostUrgentDaysOut: mostUrgentDaysOut([][]int{{30, 60}, {7, 30, 60}}),
+		Severity:          "critical",
+	}
+	noneAtRisk := ReportSubjectStructure{ClusterName: "ocp.example.com", TotalCerts: "12", ExpiringCerts: "0", MostUrgentDaysOut: mostUrgentDaysOut(nil)}
+	urgentSubject := `[{{ .ClusterName }}] {{ if .MostUrgentDaysOut }}{{ .ExpiringCerts }} certificates expire within {{ .MostUrgentDaysOut }} days
+		{{- else }}no certificates at risk{{ end }}`
+
+	tests := []struct {
+		name             string
+		smtpSubject      string
+		subjectStructure ReportSubjectStructure
+		want             string
+	}{
+		{
+			name:             "default subject",
+			subjectStructure: subjectStructure,
+			want:             SMTPReportSubject,
+		},
+		{
+			name:             "custom template on one line",
+			smtpSubject:      "[{{ .Severity }}] {{ .ExpiringCerts }}/{{ .TotalCerts }} certificates in {{ .ClusterName }}\n  need attention",
+			subjectStructure: subjectStructure,
+			want:             "[critical] 3/12 certificates in ocp.example.com need attention",
+		},
+		{
+			name:             "most urgent days out",
+			smtpSubject:      urgentSubject,
+			subjectStructure: subjectStructure,
+			want:             "[ocp.example.com] 3 certificates expire within 7 days",
+		},
+		{
+			name:             "no certificates at risk",
+			smtpSubject:      urgentSubject,
+			subjectStructure: noneAtRisk,
+			want:             "[ocp.example.com] no certificates at risk",
+		},
+		{
+			name:             "template that does not parse falls back to the default",
+			smtpSubject:      "{{ .ClusterName ",
+			subjectStructure: subjectStructure,
+			want:             SMTPReportSubject,
+		},
+		{
+			name:             "template with an unknown field falls back to the default",
+			smtpSubject:      "{{ .Cluster }} report",
+			subjectStructure: subjectStructure,
+			want:             SMTPReportSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			alert := configv1.Alert{AlertConfiguration: configv1.AlertConfiguration{SMTPSubject: tt.smtpSubject}}
+			reportTemplates := loadReportTemplates(certificateReportTemplates(), alert, "sentinel", lggr, fake.NewClientBuilder().Build())
+			g.Expect(renderReportSubject(reportTemplates.Subject, tt.subjectStructure, lggr)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestReportClusterName(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(reportClusterName(configv1.AlertConfiguration{ClusterName: "production"}, "https://api.ocp.example.com:6443")).To(Equal("production"))
+	g.Expect(reportClusterName(configv1.AlertConfiguration{}, "https://api.ocp.example.com:6443")).To(Equal("ocp.example.com"))
+	g.Expect(reportClusterName(configv1.AlertConfiguration{}, "https://kubernetes.default.svc")).To(Equal("kubernetes.default.svc"))
+	g.Expect(reportClusterName(configv1.AlertConfiguration{}, "not a URL")).To(Equal("not a URL"))
+}
+
+func TestMostUrgentDaysOut(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(mostUrgentDaysOut(nil)).To(BeZero())
+	g.Expect(mostUrgentDaysOut([][]int{{}, {}})).To(BeZero())
+	g.Expect(mostUrgentDaysOut([][]int{{90, 30}, {60}, {14, 90}})).To(Equal(14))
+}
+
+func TestLoadReportTemplates(t *testing.T) {
+	builtIn := certificateReportTemplates()
+	clnt := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-templates", Namespace: "sentinel"},
+		Data: map[string]string{
+			ReportTemplateSubjectKey: "[{{ .ClusterName }}] certificates at risk",
+			ReportTemplateTextRowKey: "{{ .Name }} expires {{ .ExpirationDate }}\n",
+			ReportTemplateHTMLRowKey: "<tr><td>{{ .Name </td></tr>",
+		},
+	}).Build()
+
+	tests := []struct {
+		name            string
+		reportTemplates string
+		want            func(reportTemplates ReportTemplates) ReportTemplates
+	}{
+		{
+			name:            "missing configmap keeps every default",
+			reportTemplates: "missing-templates",
+			want:            func(reportTemplates ReportTemplates) ReportTemplates { return reportTemplates },
+		},
+		{
+			name:            "overrides the keys that are set",
+			reportTemplates: "report-templates",
+			want: func(reportTemplates ReportTemplates) ReportTemplates {
+				// The keys missing from the ConfigMap keep the default, as does the HTML row that fails to parse
+				reportTemplates.Subject = "[{{ .ClusterName }}] certificates at risk"
+				reportTemplates.TextRow = "{{ .Name }} expires {{ .ExpirationDate }}\n"
+				return reportTemplates
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			alert := configv1.Alert{ReportTemplates: tt.reportTemplates}
+			g.Expect(loadReportTemplates(builtIn, alert, "sentinel", lggr, clnt)).To(Equal(tt.want(builtIn)))
+		})
+	}
+}
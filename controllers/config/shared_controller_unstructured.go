@@ -0,0 +1,113 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SelectedData is a single value located in an object by a DataSelector
+type SelectedData struct {
+	// DataKey is the DataSelector name, or JSONPath expression, with an index affixed when more than one value matched
+	DataKey string
+	// Data is the located value, decoded from base64 if the DataSelector asked for it
+	Data []byte
+}
+
+// GetUnstructuredList returns a list of objects of any apiVersion and kind with the provided ListOptions
+func GetUnstructuredList(apiVersion string, kind string, listOptions *client.ListOptions, clnt client.Client) (*unstructured.UnstructuredList, error) {
+	objectList := &unstructured.UnstructuredList{}
+	objectList.SetGroupVersionKind(schema.FromAPIVersionAndKind(apiVersion, kind+"List"))
+
+	err := clnt.List(context.Background(), objectList, listOptions)
+	if err != nil {
+		return objectList, err
+	}
+	return objectList, nil
+}
+
+// SelectDataFromObject runs the DataSelectors against an unstructured object and returns every string value they located
+func SelectDataFromObject(obj unstructured.Unstructured, dataSelectors []configv1.DataSelector) []SelectedData {
+	var selectedData []SelectedData
+
+	for _, selector := range dataSelectors {
+		dataKey := selector.Name
+		if dataKey == "" {
+			dataKey = selector.JSONPath
+		}
+
+		// Allow bare expressions such as .spec.caCert in addition to {.spec.caCert}
+		expression := strings.TrimSpace(selector.JSONPath)
+		if !strings.HasPrefix(expression, "{") {
+			expression = "{" + expression + "}"
+		}
+
+		jp := jsonpath.New(dataKey).AllowMissingKeys(true)
+		if err := jp.Parse(expression); err != nil {
+			lggr.Error(err, "Failed to parse JSONPath expression "+selector.JSONPath)
+			continue
+		}
+
+		results, err := jp.FindResults(obj.Object)
+		if err != nil {
+			lggr.Error(err, "Failed to evaluate JSONPath expression "+selector.JSONPath+" on "+obj.GetKind()+"/"+obj.GetName())
+			continue
+		}
+
+		// Flatten the results, only string values can hold certificate or keystore data
+		var values []string
+		for _, result := range results {
+			for _, value := range result {
+				if !value.IsValid() || !value.CanInterface() {
+					continue
+				}
+				if str, ok := value.Interface().(string); ok && str != "" {
+					values = append(values, str)
+				}
+			}
+		}
+
+		for i, value := range values {
+			data := []byte(value)
+			if selector.Base64Decode {
+				data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+				if err != nil {
+					lggr.Error(err, fmt.Sprintf("Failed to base64 decode %s on %s/%s", selector.JSONPath, obj.GetKind(), obj.GetName()))
+					continue
+				}
+			}
+
+			selectedKey := dataKey
+			if len(values) > 1 {
+				selectedKey = dataKey + "[" + strconv.Itoa(i) + "]"
+			}
+			selectedData = append(selectedData, SelectedData{DataKey: selectedKey, Data: data})
+		}
+	}
+
+	return selectedData
+}
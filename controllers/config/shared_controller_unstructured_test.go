@@ -0,0 +1,106 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/base64"
+	"testing"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newTestUnstructuredObject returns a custom resource holding PEM, base64, list, and non-string values
+func newTestUnstructuredObject() unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]interface{}{"name": "edge", "namespace": "apps"},
+		"spec": map[string]interface{}{
+			"port": int64(443),
+			"tls": map[string]interface{}{
+				"certificate":   "-----BEGIN CERTIFICATE-----\nleaf\n-----END CERTIFICATE-----\n",
+				"caCertificate": "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n",
+			},
+			"caBundle": base64.StdEncoding.EncodeToString([]byte("bundle")) + "\n",
+			"clientCAs": []interface{}{
+				map[string]interface{}{"name": "a", "ca": "client-a"},
+				map[string]interface{}{"name": "b", "ca": "client-b"},
+				map[string]interface{}{"name": "c", "ca": ""},
+			},
+			"keystores": []interface{}{"not base64!", base64.StdEncoding.EncodeToString([]byte("keystore"))},
+		},
+	}}
+}
+
+func TestSelectDataFromObject(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []configv1.DataSelector
+		expected  []SelectedData
+	}{
+		{
+			name:      "selects a bare expression keyed by the expression",
+			selectors: []configv1.DataSelector{{JSONPath: ".spec.tls.certificate"}},
+			expected:  []SelectedData{{DataKey: ".spec.tls.certificate", Data: []byte("-----BEGIN CERTIFICATE-----\nleaf\n-----END CERTIFICATE-----\n")}},
+		},
+		{
+			name:      "selects a braced expression keyed by the selector name",
+			selectors: []configv1.DataSelector{{Name: "ca", JSONPath: "{.spec.tls.caCertificate}"}},
+			expected:  []SelectedData{{DataKey: "ca", Data: []byte("-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n")}},
+		},
+		{
+			name:      "decodes base64 values",
+			selectors: []configv1.DataSelector{{Name: "bundle", JSONPath: ".spec.caBundle", Base64Decode: true}},
+			expected:  []SelectedData{{DataKey: "bundle", Data: []byte("bundle")}},
+		},
+		{
+			name:      "affixes an index when more than one value matches, skipping empty values",
+			selectors: []configv1.DataSelector{{Name: "client-ca", JSONPath: ".spec.clientCAs[*].ca"}},
+			expected:  []SelectedData{{DataKey: "client-ca[0]", Data: []byte("client-a")}, {DataKey: "client-ca[1]", Data: []byte("client-b")}},
+		},
+		{
+			name:      "skips values that are not valid base64",
+			selectors: []configv1.DataSelector{{Name: "keystore", JSONPath: ".spec.keystores[*]", Base64Decode: true}},
+			expected:  []SelectedData{{DataKey: "keystore[1]", Data: []byte("keystore")}},
+		},
+		{
+			name:      "skips a single value that is not valid base64",
+			selectors: []configv1.DataSelector{{JSONPath: ".spec.tls.certificate", Base64Decode: true}},
+		},
+		{
+			name:      "skips values that are not strings",
+			selectors: []configv1.DataSelector{{JSONPath: ".spec.port"}, {JSONPath: ".spec.tls"}},
+		},
+		{
+			name:      "skips missing paths",
+			selectors: []configv1.DataSelector{{JSONPath: ".spec.missing.certificate"}},
+		},
+		{
+			name:      "skips invalid expressions and keeps running the other selectors",
+			selectors: []configv1.DataSelector{{Name: "invalid", JSONPath: "{.spec.tls[}"}, {Name: "ca", JSONPath: ".spec.tls.caCertificate"}},
+			expected:  []SelectedData{{DataKey: "ca", Data: []byte("-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n")}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(SelectDataFromObject(newTestUnstructuredObject(), test.selectors)).To(Equal(test.expected))
+		})
+	}
+}
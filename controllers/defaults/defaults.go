@@ -4,9 +4,21 @@ package defaults
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
-const ()
+const (
+	// AnnotationPrefix is the prefix of the annotations app teams can set on their objects to control how they are scanned
+	AnnotationPrefix = "certificate-sentinel.polyglot.systems/"
+	// AnnotationIgnore skips the annotated object when set to true
+	AnnotationIgnore = AnnotationPrefix + "ignore"
+	// AnnotationDaysOut overrides the target DaysOut for the annotated object with a comma separated list of days
+	AnnotationDaysOut = AnnotationPrefix + "days-out"
+	// AnnotationOwner sets the routing contact for the certificates found in the annotated object
+	AnnotationOwner = AnnotationPrefix + "owner"
+	// AnnotationIgnoreKeys skips the listed comma separated data keys of the annotated object
+	AnnotationIgnoreKeys = AnnotationPrefix + "ignore-keys"
+)
 
 var (
 	// ScanningInterval is the number of seconds to wait before the controller starts again
@@ -15,12 +27,40 @@ var (
 	LogLevel = 2
 	// DaysOut is the default number of days out to gate certificate expiration at
 	DaysOut = []int{30, 60, 90}
+	// Severities is the default mapping of days out to the severity of certificates expiring within them
+	Severities = map[int]string{7: "critical", 30: "warning", 90: "info"}
+	// PolicyMinimumRSAKeySize is the default minimum number of bits in an RSA key before a target policy flags it as weak
+	PolicyMinimumRSAKeySize = 2048
+	// TrustBundleKey is the default key holding the trusted roots in a chain verification trust bundle ConfigMap
+	TrustBundleKey = "ca-bundle.crt"
+	// RevocationCheckTimeout is the default number of seconds to wait on each OCSP or CRL request
+	RevocationCheckTimeout = 10
+	// RevocationScanTimeout is the default number of seconds allowed for all of the revocation checks in a scan
+	RevocationScanTimeout = 60
 	// ReportInterval is how frequently a report should be submitted for triggered targeted alerts
 	ReportInterval = "daily"
 	// SMTPAuthUseSSL is a boolean for if the Golang SMTP Client will use TLS against the server
 	SMTPAuthUseSSL = true
 	// SMTPAuthUseSTARTTLS is a boolean for if the Golang SMTP Client will use STARTTLS against the server
 	SMTPAuthUseSTARTTLS = true
+	// XOAUTH2TokenTimeout is the number of seconds to wait on the OAuth2 token endpoint for an XOAUTH2 access token
+	XOAUTH2TokenTimeout = 10
+	// XOAUTH2TokenSourcesMax is the number of OAuth2 clients whose token sources are cached before the cache is emptied
+	XOAUTH2TokenSourcesMax = 64
+	// TLSProbeTimeout is the number of seconds to wait on each probed TLS endpoint
+	TLSProbeTimeout = 5
+	// TLSProbePorts are the Service ports dialed when no ports are specified
+	TLSProbePorts = []int{443, 8443}
+	// TLSProbePortNames are the Service port names dialed when no ports are specified
+	TLSProbePortNames = []string{"https", "tls"}
+	// SystemNamespaces is the preset of namespace patterns removed when a target excludes system namespaces
+	SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease", "openshift", "openshift-*"}
+	// SMTPRetryBackoff is the delay before a failed delivery is retried on a later scan, doubled on each following failure
+	SMTPRetryBackoff = time.Minute
+	// SMTPRetryBackoffMax caps the delay between delivery retries
+	SMTPRetryBackoffMax = time.Hour
+	// RevocationFailureBackoff is how long an OCSP responder, CRL distribution point, or AIA URL that failed is skipped before it is requested again
+	RevocationFailureBackoff = 15 * time.Minute
 	// SMTPMessageSubject is the default subject sent with emailed messages
 	SMTPMessageSubject = "Certificate Sentinel Operator - Report"
 )
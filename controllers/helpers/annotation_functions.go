@@ -0,0 +1,89 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+)
+
+/*=====================================================================================
+| Object Annotation Helper Functions
+=====================================================================================*/
+
+// ObjectAnnotations are the per-object scanning overrides set with the certificate-sentinel.polyglot.systems/ annotations
+type ObjectAnnotations struct {
+	// Ignore skips the object entirely
+	Ignore bool
+	// DaysOut overrides the target DaysOut for the object when set
+	DaysOut []int
+	// Owner is the routing contact for the certificates found in the object
+	Owner string
+	// IgnoreKeys are the data keys of the object to skip
+	IgnoreKeys []string
+}
+
+// ParseObjectAnnotations reads the scanning overrides from an object's annotations
+// An error is returned for an invalid days-out annotation, the other overrides are still returned
+func ParseObjectAnnotations(annotations map[string]string) (ObjectAnnotations, error) {
+	var objectAnnotations ObjectAnnotations
+	var err error
+
+	if ignore, ok := annotations[defaults.AnnotationIgnore]; ok {
+		objectAnnotations.Ignore, _ = strconv.ParseBool(strings.TrimSpace(ignore))
+	}
+
+	objectAnnotations.Owner = strings.TrimSpace(annotations[defaults.AnnotationOwner])
+	objectAnnotations.IgnoreKeys = splitAnnotationList(annotations[defaults.AnnotationIgnoreKeys])
+
+	for _, day := range splitAnnotationList(annotations[defaults.AnnotationDaysOut]) {
+		dayInt, convErr := strconv.Atoi(day)
+		if convErr != nil || dayInt <= 0 {
+			objectAnnotations.DaysOut = nil
+			err = errors.New("invalid " + defaults.AnnotationDaysOut + " annotation value: " + annotations[defaults.AnnotationDaysOut])
+			break
+		}
+		objectAnnotations.DaysOut = append(objectAnnotations.DaysOut, dayInt)
+	}
+
+	return objectAnnotations, err
+}
+
+// IgnoresKey checks if a data key is listed in the ignore-keys annotation
+func (o ObjectAnnotations) IgnoresKey(key string) bool {
+	for _, k := range o.IgnoreKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAnnotationList splits a comma separated annotation value into its trimmed, non-empty items
+func splitAnnotationList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
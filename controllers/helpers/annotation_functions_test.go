@@ -0,0 +1,55 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseObjectAnnotations", func() {
+	It("returns no overrides for an object without annotations", func() {
+		objectAnnotations, err := ParseObjectAnnotations(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objectAnnotations).To(Equal(ObjectAnnotations{}))
+	})
+
+	It("reads every override", func() {
+		objectAnnotations, err := ParseObjectAnnotations(map[string]string{
+			"certificate-sentinel.polyglot.systems/ignore":      "true",
+			"certificate-sentinel.polyglot.systems/days-out":    "7, 14",
+			"certificate-sentinel.polyglot.systems/owner":       "team-x@example.com",
+			"certificate-sentinel.polyglot.systems/ignore-keys": "ca.crt,tls.crt",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objectAnnotations.Ignore).To(BeTrue())
+		Expect(objectAnnotations.DaysOut).To(Equal([]int{7, 14}))
+		Expect(objectAnnotations.Owner).To(Equal("team-x@example.com"))
+		Expect(objectAnnotations.IgnoresKey("ca.crt")).To(BeTrue())
+		Expect(objectAnnotations.IgnoresKey("tls.key")).To(BeFalse())
+	})
+
+	It("keeps the other overrides when days-out is invalid", func() {
+		objectAnnotations, err := ParseObjectAnnotations(map[string]string{
+			"certificate-sentinel.polyglot.systems/days-out": "7,soon",
+			"certificate-sentinel.polyglot.systems/owner":    "team-x@example.com",
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(objectAnnotations.DaysOut).To(BeNil())
+		Expect(objectAnnotations.Owner).To(Equal("team-x@example.com"))
+	})
+})
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/x509"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseCertificateDetails", func() {
+	It("collects the SANs, key, signature, and usages of a certificate", func() {
+		cert, err := x509.ParseCertificate(newTestTLSCertificate("app.example.com").Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+
+		details := ParseCertificateDetails(cert)
+		Expect(details.Subject).To(Equal("CN=app.example.com"))
+		Expect(details.SerialNumber).To(Equal("01"))
+		Expect(details.FingerprintSHA256).To(HaveLen(95))
+		Expect(details.DNSNames).To(Equal([]string{"app.example.com"}))
+		Expect(details.PublicKeyAlgorithm).To(Equal("ECDSA"))
+		Expect(details.PublicKeySize).To(Equal(256))
+		Expect(details.SignatureAlgorithm).To(Equal("ECDSA-SHA256"))
+		Expect(details.KeyUsage).To(Equal([]string{"DigitalSignature"}))
+		Expect(details.ExtendedKeyUsage).To(Equal([]string{"ServerAuth"}))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
+/*=====================================================================================
+| Certificate Chain Helper Functions
+=====================================================================================*/
+
+const (
+	// ChainRuleBroken flags chains that do not verify up to a trusted root
+	ChainRuleBroken = "broken-chain"
+	// ChainRuleMissingIntermediate flags chains that end before a self-signed root because an issuer is not in the bundle
+	ChainRuleMissingIntermediate = "missing-intermediate"
+	// ChainRuleOutOfOrder flags bundles that are not ordered from the leaf up to its issuers
+	ChainRuleOutOfOrder = "out-of-order-chain"
+	// ChainRuleIntermediateExpiresFirst flags chains with an issuer that expires before the leaf
+	ChainRuleIntermediateExpiresFirst = "intermediate-expires-first"
+)
+
+// VerifyCertificateChain assembles the certificates decoded from a single data key into a chain and verifies it against the roots, or the system roots when nil
+// It returns the index of the leaf certificate the findings belong to, or -1 when the bundle only holds Certificate Authorities
+func VerifyCertificateChain(certs []*x509.Certificate, roots *x509.CertPool, now time.Time) (int, []configv1.PolicyViolation) {
+	leafIndex := -1
+	for i, cert := range certs {
+		if !cert.IsCA {
+			leafIndex = i
+			break
+		}
+	}
+	// A bundle of only CAs, such as a ca.crt, is a set of trust anchors and not a chain
+	if leafIndex == -1 {
+		return -1, nil
+	}
+	leaf := certs[leafIndex]
+	var violations []configv1.PolicyViolation
+
+	// Follow the issuers of the leaf through the bundle
+	assembled, positions := assembleBundleChain(leafIndex, certs)
+	for i, position := range positions {
+		if position != i {
+			violations = append(violations, configv1.PolicyViolation{Rule: ChainRuleOutOfOrder, Severity: SeverityWarning, Message: "The bundle is not ordered from the leaf up to its issuers"})
+			break
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for i, cert := range certs {
+		if i != leafIndex {
+			intermediates.AddCert(cert)
+		}
+	}
+	// Verify at a time every certificate in the bundle chain is valid so expired issuers are reported by their expiry instead of as an untrusted chain
+	verifyTime := now
+	for _, cert := range assembled {
+		if cert.NotBefore.After(verifyTime) || cert.NotAfter.Before(now) {
+			verifyTime = latestNotBefore(assembled)
+			break
+		}
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, CurrentTime: verifyTime, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+
+	chain := assembled
+	var invalidErr x509.CertificateInvalidError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	switch {
+	case err == nil:
+		chain = chains[0]
+	case errors.As(err, &invalidErr) && (invalidErr.Reason == x509.Expired):
+		// Expiry is reported by the days out thresholds and the issuer expiry check below
+	case errors.As(err, &unknownAuthorityErr) && !isSelfSigned(assembled[len(assembled)-1]):
+		top := assembled[len(assembled)-1]
+		violations = append(violations, configv1.PolicyViolation{Rule: ChainRuleMissingIntermediate, Severity: SeverityCritical, Message: "No trusted issuer found for " + top.Issuer.String() + ", the bundle may be missing an intermediate"})
+	default:
+		violations = append(violations, configv1.PolicyViolation{Rule: ChainRuleBroken, Severity: SeverityCritical, Message: err.Error()})
+	}
+
+	// A valid leaf behind an issuer that expires first will still break
+	for _, issuer := range chain[1:] {
+		if !issuer.NotAfter.Before(leaf.NotAfter) {
+			continue
+		}
+		severity := SeverityWarning
+		if issuer.NotAfter.Before(now) {
+			severity = SeverityCritical
+		}
+		violations = append(violations, configv1.PolicyViolation{Rule: ChainRuleIntermediateExpiresFirst, Severity: severity, Message: "Issuer " + issuer.Subject.CommonName + " expires " + issuer.NotAfter.UTC().Format(time.RFC822Z) + ", before the leaf"})
+	}
+
+	return leafIndex, violations
+}
+
+// assembleBundleChain follows the issuer of each certificate through the bundle starting at the leaf, returning the chain and the bundle position of each of its certificates
+func assembleBundleChain(leafIndex int, certs []*x509.Certificate) ([]*x509.Certificate, []int) {
+	chain := []*x509.Certificate{certs[leafIndex]}
+	positions := []int{leafIndex}
+	used := map[int]bool{leafIndex: true}
+
+	for current := certs[leafIndex]; !isSelfSigned(current); {
+		next := -1
+		for i, cert := range certs {
+			if !used[i] && bytes.Equal(current.RawIssuer, cert.RawSubject) && cert.IsCA {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			break
+		}
+		used[next] = true
+		current = certs[next]
+		chain = append(chain, current)
+		positions = append(positions, next)
+	}
+
+	return chain, positions
+}
+
+// latestNotBefore returns the latest NotBefore of the certificates
+func latestNotBefore(certs []*x509.Certificate) time.Time {
+	var latest time.Time
+	for _, cert := range certs {
+		if cert.NotBefore.After(latest) {
+			latest = cert.NotBefore
+		}
+	}
+	return latest
+}
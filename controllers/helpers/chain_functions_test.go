@@ -0,0 +1,118 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newTestChainCertificate creates a certificate signed by the parent, or self-signed when the parent is nil
+func newTestChainCertificate(commonName string, isCA bool, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{commonName}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return cert, key
+}
+
+var _ = Describe("VerifyCertificateChain", func() {
+	var root, intermediate, leaf *x509.Certificate
+	var roots *x509.CertPool
+	now := time.Now()
+
+	BeforeEach(func() {
+		var rootKey, intermediateKey *ecdsa.PrivateKey
+		root, rootKey = newTestChainCertificate("Test Root", true, now.AddDate(10, 0, 0), nil, nil)
+		intermediate, intermediateKey = newTestChainCertificate("Test Intermediate", true, now.AddDate(5, 0, 0), root, rootKey)
+		leaf, _ = newTestChainCertificate("app.example.com", false, now.AddDate(1, 0, 0), intermediate, intermediateKey)
+		roots = x509.NewCertPool()
+		roots.AddCert(root)
+	})
+
+	It("passes an ordered chain up to a trusted root", func() {
+		leafIndex, violations := VerifyCertificateChain([]*x509.Certificate{leaf, intermediate}, roots, now)
+		Expect(leafIndex).To(Equal(0))
+		Expect(violations).To(BeEmpty())
+	})
+
+	It("skips bundles of only Certificate Authorities", func() {
+		leafIndex, violations := VerifyCertificateChain([]*x509.Certificate{root, intermediate}, roots, now)
+		Expect(leafIndex).To(Equal(-1))
+		Expect(violations).To(BeEmpty())
+	})
+
+	It("flags a missing intermediate", func() {
+		_, violations := VerifyCertificateChain([]*x509.Certificate{leaf}, roots, now)
+		Expect(policyRules(violations)).To(Equal([]string{ChainRuleMissingIntermediate}))
+	})
+
+	It("flags an out of order bundle", func() {
+		leafIndex, violations := VerifyCertificateChain([]*x509.Certificate{intermediate, leaf}, roots, now)
+		Expect(leafIndex).To(Equal(1))
+		Expect(policyRules(violations)).To(Equal([]string{ChainRuleOutOfOrder}))
+	})
+
+	It("flags a chain up to an untrusted root", func() {
+		_, violations := VerifyCertificateChain([]*x509.Certificate{leaf, intermediate, root}, x509.NewCertPool(), now)
+		Expect(policyRules(violations)).To(Equal([]string{ChainRuleBroken}))
+	})
+
+	It("flags an intermediate that expires before the leaf", func() {
+		var rootKey *ecdsa.PrivateKey
+		root, rootKey = newTestChainCertificate("Test Root", true, now.AddDate(10, 0, 0), nil, nil)
+		shortIntermediate, shortKey := newTestChainCertificate("Short Intermediate", true, now.AddDate(0, 1, 0), root, rootKey)
+		longLeaf, _ := newTestChainCertificate("app.example.com", false, now.AddDate(1, 0, 0), shortIntermediate, shortKey)
+		roots = x509.NewCertPool()
+		roots.AddCert(root)
+
+		_, violations := VerifyCertificateChain([]*x509.Certificate{longLeaf, shortIntermediate}, roots, now)
+		Expect(policyRules(violations)).To(Equal([]string{ChainRuleIntermediateExpiresFirst}))
+		Expect(violations[0].Severity).To(Equal(SeverityWarning))
+
+		_, violations = VerifyCertificateChain([]*x509.Certificate{longLeaf, shortIntermediate}, roots, now.AddDate(0, 2, 0))
+		Expect(policyRules(violations)).To(Equal([]string{ChainRuleIntermediateExpiresFirst}))
+		Expect(violations[0].Severity).To(Equal(SeverityCritical))
+	})
+})
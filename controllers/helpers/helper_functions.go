@@ -4,6 +4,7 @@ package helpers
 import (
 	"math"
 	"strings"
+	"time"
 )
 
 //============================================================================================  HELPER FUNCTIONS
@@ -91,3 +92,14 @@ func RemoveStringFromSlice(r string, s []string) []string {
 	}
 	return s
 }
+
+// RetryBackoff returns the delay before retrying after the number of failures in a row, doubling the delay after each failure up to maxDelay
+func RetryBackoff(failures int, delay time.Duration, maxDelay time.Duration) time.Duration {
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay = delay * 2
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
+/*=====================================================================================
+| Private Key Helper Functions
+=====================================================================================*/
+
+const (
+	// KeyPairRuleMismatch flags a private key that does not belong to the certificate it is stored with
+	KeyPairRuleMismatch = "key-mismatch"
+	// KeyPairRuleEncrypted flags a passphrase protected private key that can not be checked against the certificate
+	KeyPairRuleEncrypted = "encrypted-key"
+	// KeyPairRuleWeakKey flags DSA, small RSA, and P-224 private keys
+	KeyPairRuleWeakKey = "weak-private-key"
+	// KeyPairRuleInvalid flags private key material that can not be parsed
+	KeyPairRuleInvalid = "invalid-private-key"
+)
+
+// privateKeyBlockTypes are the PEM block types recognised as private key material
+var privateKeyBlockTypes = []string{"RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY", "DSA PRIVATE KEY"}
+
+// DecodePrivateKeyBlock returns the first private key PEM block in the data, or nil when it holds no private key material
+func DecodePrivateKeyBlock(s []byte) *pem.Block {
+	rest := s
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil
+		}
+		for _, blockType := range privateKeyBlockTypes {
+			if block.Type == blockType {
+				return block
+			}
+		}
+	}
+}
+
+// MatchingPrivateKey finds the private key stored alongside the certificate data key in a Secret - in the same value, the TLS Secret tls.key, a key sharing the certificate file name such as server.key for server.crt, or the only private key in a Secret holding a single leaf certificate
+func MatchingPrivateKey(data map[string][]byte, certificateKey string) (string, *pem.Block) {
+	if block := DecodePrivateKeyBlock(data[certificateKey]); block != nil {
+		return certificateKey, block
+	}
+
+	candidates := []string{}
+	if certificateKey == "tls.crt" {
+		candidates = append(candidates, "tls.key")
+	}
+	base := strings.TrimSuffix(certificateKey, path.Ext(certificateKey))
+	candidates = append(candidates, base+".key", base+"-key.pem", base+".key.pem")
+	for _, candidate := range candidates {
+		if candidate == certificateKey {
+			continue
+		}
+		if block := DecodePrivateKeyBlock(data[candidate]); block != nil {
+			return candidate, block
+		}
+	}
+
+	// Fall back to a lone private key only when the Secret holds a single certificate key that is not a CA - the ca.crt of an issued TLS Secret is stored next to a tls.key that is not its own
+	if certificateKey == "ca.crt" {
+		return "", nil
+	}
+	if cert := firstCertificate(data[certificateKey]); cert == nil || cert.IsCA {
+		return "", nil
+	}
+	var keyNames []string
+	for k, v := range data {
+		if k == certificateKey {
+			continue
+		}
+		if firstCertificate(v) != nil {
+			return "", nil
+		}
+		if DecodePrivateKeyBlock(v) != nil {
+			keyNames = append(keyNames, k)
+		}
+	}
+	if len(keyNames) == 1 {
+		return keyNames[0], DecodePrivateKeyBlock(data[keyNames[0]])
+	}
+	return "", nil
+}
+
+// firstCertificate returns the first certificate PEM block in the data parsed, or nil when it holds none
+func firstCertificate(s []byte) *x509.Certificate {
+	rest := s
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil
+			}
+			return cert
+		}
+	}
+}
+
+// ParsePrivateKeyBlock parses a PKCS#1, PKCS#8, or EC private key block and returns its public key
+func ParsePrivateKeyBlock(block *pem.Block) (crypto.PublicKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public(), nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public(), nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported PKCS#8 private key type %T", key)
+		}
+		return signer.Public(), nil
+	}
+	return nil, errors.New("unsupported private key type " + block.Type)
+}
+
+// isEncryptedPrivateKeyBlock returns if the block is a passphrase protected PKCS#8 key or a legacy OpenSSL encrypted PEM block
+func isEncryptedPrivateKeyBlock(block *pem.Block) bool {
+	return block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+}
+
+// ValidateKeyPair checks the private key block stored with a certificate and returns any findings - a key that does not match the certificate, an encrypted key that can not be checked, or a weak key type
+func ValidateKeyPair(cert *x509.Certificate, block *pem.Block, minimumRSAKeySize int) []configv1.PolicyViolation {
+	if isEncryptedPrivateKeyBlock(block) {
+		return []configv1.PolicyViolation{{Rule: KeyPairRuleEncrypted, Severity: SeverityWarning, Message: "The private key is encrypted and can not be checked against the certificate"}}
+	}
+	if block.Type == "DSA PRIVATE KEY" {
+		return []configv1.PolicyViolation{{Rule: KeyPairRuleWeakKey, Severity: SeverityCritical, Message: "DSA private keys are not supported for TLS"}}
+	}
+
+	publicKey, err := ParsePrivateKeyBlock(block)
+	if err != nil {
+		return []configv1.PolicyViolation{{Rule: KeyPairRuleInvalid, Severity: SeverityCritical, Message: "Failed to parse the private key: " + err.Error()}}
+	}
+
+	var violations []configv1.PolicyViolation
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minimumRSAKeySize {
+			violations = append(violations, configv1.PolicyViolation{Rule: KeyPairRuleWeakKey, Severity: SeverityCritical, Message: fmt.Sprintf("RSA private key is %d bits, under the minimum of %d", key.N.BitLen(), minimumRSAKeySize)})
+		}
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P224() {
+			violations = append(violations, configv1.PolicyViolation{Rule: KeyPairRuleWeakKey, Severity: SeverityCritical, Message: "EC private key uses the P-224 curve"})
+		}
+	}
+
+	// Every key type the x509 package parses implements Equal
+	if equaler, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !equaler.Equal(cert.PublicKey) {
+		violations = append(violations, configv1.PolicyViolation{Rule: KeyPairRuleMismatch, Severity: SeverityCritical, Message: "The private key does not match the certificate " + cert.Subject.CommonName})
+	}
+	return violations
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateKeyPair", func() {
+	var cert *x509.Certificate
+	var key *ecdsa.PrivateKey
+
+	BeforeEach(func() {
+		cert, key = newTestChainCertificate("app.example.com", false, time.Now().AddDate(1, 0, 0), nil, nil)
+	})
+
+	It("passes the matching EC and PKCS#8 keys", func() {
+		ecDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ValidateKeyPair(cert, &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, 2048)).To(BeEmpty())
+
+		pkcs8DER, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ValidateKeyPair(cert, &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}, 2048)).To(BeEmpty())
+	})
+
+	It("flags a key from another certificate", func() {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(otherKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policyRules(ValidateKeyPair(cert, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, 2048))).To(Equal([]string{KeyPairRuleMismatch}))
+	})
+
+	It("flags small PKCS#1 RSA keys as weak and mismatched", func() {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+		Expect(policyRules(ValidateKeyPair(cert, block, 2048))).To(Equal([]string{KeyPairRuleWeakKey, KeyPairRuleMismatch}))
+	})
+
+	It("flags encrypted and unparsable keys", func() {
+		Expect(policyRules(ValidateKeyPair(cert, &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("sealed")}, 2048))).To(Equal([]string{KeyPairRuleEncrypted}))
+		Expect(policyRules(ValidateKeyPair(cert, &pem.Block{Type: "RSA PRIVATE KEY", Headers: map[string]string{"Proc-Type": "4,ENCRYPTED"}, Bytes: []byte("sealed")}, 2048))).To(Equal([]string{KeyPairRuleEncrypted}))
+		Expect(policyRules(ValidateKeyPair(cert, &pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}, 2048))).To(Equal([]string{KeyPairRuleInvalid}))
+	})
+})
+
+var _ = Describe("MatchingPrivateKey", func() {
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")})
+
+	It("pairs tls.crt with tls.key", func() {
+		name, block := MatchingPrivateKey(map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM, "other.key": keyPEM}, "tls.crt")
+		Expect(name).To(Equal("tls.key"))
+		Expect(block).NotTo(BeNil())
+	})
+
+	It("pairs keys by file name and combined PEM files", func() {
+		name, _ := MatchingPrivateKey(map[string][]byte{"server.crt": certPEM, "server.key": keyPEM, "client.key": keyPEM}, "server.crt")
+		Expect(name).To(Equal("server.key"))
+		name, _ = MatchingPrivateKey(map[string][]byte{"bundle.pem": append(append([]byte{}, certPEM...), keyPEM...)}, "bundle.pem")
+		Expect(name).To(Equal("bundle.pem"))
+	})
+
+	It("falls back to a lone private key only for a single leaf certificate", func() {
+		ca, caKey := newTestChainCertificate("Test CA", true, time.Now().AddDate(5, 0, 0), nil, nil)
+		leaf, _ := newTestChainCertificate("app.example.com", false, time.Now().AddDate(1, 0, 0), ca, caKey)
+		caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+		leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+
+		name, _ := MatchingPrivateKey(map[string][]byte{"cert": leafPEM, "key": keyPEM}, "cert")
+		Expect(name).To(Equal("key"))
+
+		// A CA certificate is never paired with a key by fallback
+		name, block := MatchingPrivateKey(map[string][]byte{"root": caPEM, "key": keyPEM}, "root")
+		Expect(name).To(BeEmpty())
+		Expect(block).To(BeNil())
+
+		// More than one key, or more than one certificate key, can not be paired by name alone
+		name, _ = MatchingPrivateKey(map[string][]byte{"cert": leafPEM, "a.key": keyPEM, "b.key": keyPEM}, "cert")
+		Expect(name).To(BeEmpty())
+		name, _ = MatchingPrivateKey(map[string][]byte{"cert": leafPEM, "other": leafPEM, "key": keyPEM}, "cert")
+		Expect(name).To(BeEmpty())
+	})
+
+	It("only pairs tls.crt in an issued TLS Secret holding a ca.crt", func() {
+		ca, caKey := newTestChainCertificate("Test CA", true, time.Now().AddDate(5, 0, 0), nil, nil)
+		leaf, _ := newTestChainCertificate("app.example.com", false, time.Now().AddDate(1, 0, 0), ca, caKey)
+		data := map[string][]byte{
+			"ca.crt":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+			"tls.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+			"tls.key": keyPEM,
+		}
+
+		name, block := MatchingPrivateKey(data, "ca.crt")
+		Expect(name).To(BeEmpty())
+		Expect(block).To(BeNil())
+		name, block = MatchingPrivateKey(data, "tls.crt")
+		Expect(name).To(Equal("tls.key"))
+		Expect(block).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/x509"
+	"sort"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+/*=====================================================================================
+| Kubeconfig Helper Functions
+=====================================================================================*/
+
+// KubeconfigCertificates is the set of certificates embedded in a single kubeconfig cluster or user entry
+type KubeconfigCertificates struct {
+	// Reference provides the context, cluster, and user names the certificates belong to
+	Reference configv1.KubeconfigReference
+	// Field is the kubeconfig field the certificates were embedded in
+	Field string
+	// Certificates are the decoded certificates
+	Certificates []*x509.Certificate
+}
+
+// IsKubeconfig does a quick check to see if the byte slice looks like a kubeconfig file with embedded certificate data
+func IsKubeconfig(s []byte) bool {
+	return bytes.Contains(s, []byte("certificate-authority-data")) || bytes.Contains(s, []byte("client-certificate-data"))
+}
+
+// DecodeKubeconfigCertificates parses a kubeconfig file and decodes every embedded cluster CA and user client certificate
+func DecodeKubeconfigCertificates(s []byte, lggr logr.Logger) ([]KubeconfigCertificates, error) {
+	var found []KubeconfigCertificates
+
+	kubeconfig, err := clientcmd.Load(s)
+	if err != nil {
+		return found, err
+	}
+
+	clustersSeen := make(map[string]bool)
+	usersSeen := make(map[string]bool)
+
+	// Sort the context names so the results are stable between scans
+	var contextNames []string
+	for name := range kubeconfig.Contexts {
+		contextNames = append(contextNames, name)
+	}
+	sort.Strings(contextNames)
+
+	// Loop through the contexts first so each certificate can be tied to the context using it
+	for _, contextName := range contextNames {
+		kubeContext := kubeconfig.Contexts[contextName]
+		if cluster, ok := kubeconfig.Clusters[kubeContext.Cluster]; ok && len(cluster.CertificateAuthorityData) > 0 {
+			clustersSeen[kubeContext.Cluster] = true
+			certs, err := DecodeCertificateBytes(cluster.CertificateAuthorityData, lggr)
+			if err == nil {
+				found = append(found, KubeconfigCertificates{Reference: configv1.KubeconfigReference{Context: contextName, Cluster: kubeContext.Cluster}, Field: "certificate-authority-data", Certificates: certs})
+			}
+		}
+		if user, ok := kubeconfig.AuthInfos[kubeContext.AuthInfo]; ok && len(user.ClientCertificateData) > 0 {
+			usersSeen[kubeContext.AuthInfo] = true
+			certs, err := DecodeCertificateBytes(user.ClientCertificateData, lggr)
+			if err == nil {
+				found = append(found, KubeconfigCertificates{Reference: configv1.KubeconfigReference{Context: contextName, User: kubeContext.AuthInfo}, Field: "client-certificate-data", Certificates: certs})
+			}
+		}
+	}
+
+	// Pick up any clusters and users that no context references
+	var clusterNames []string
+	for name := range kubeconfig.Clusters {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+	for _, clusterName := range clusterNames {
+		cluster := kubeconfig.Clusters[clusterName]
+		if clustersSeen[clusterName] || len(cluster.CertificateAuthorityData) == 0 {
+			continue
+		}
+		certs, err := DecodeCertificateBytes(cluster.CertificateAuthorityData, lggr)
+		if err == nil {
+			found = append(found, KubeconfigCertificates{Reference: configv1.KubeconfigReference{Cluster: clusterName}, Field: "certificate-authority-data", Certificates: certs})
+		}
+	}
+
+	var userNames []string
+	for name := range kubeconfig.AuthInfos {
+		userNames = append(userNames, name)
+	}
+	sort.Strings(userNames)
+	for _, userName := range userNames {
+		user := kubeconfig.AuthInfos[userName]
+		if usersSeen[userName] || len(user.ClientCertificateData) == 0 {
+			continue
+		}
+		certs, err := DecodeCertificateBytes(user.ClientCertificateData, lggr)
+		if err == nil {
+			found = append(found, KubeconfigCertificates{Reference: configv1.KubeconfigReference{User: userName}, Field: "client-certificate-data", Certificates: certs})
+		}
+	}
+
+	return found, nil
+}
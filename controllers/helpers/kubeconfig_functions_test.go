@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// kubeconfigCertificateData base64 encodes a certificate the way kubeconfig *-data fields hold it
+func kubeconfigCertificateData(cert *x509.Certificate) string {
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+var _ = Describe("DecodeKubeconfigCertificates", func() {
+	table.DescribeTable("decodes the embedded certificates",
+		func(kubeconfig string, expected []string, expectErr bool) {
+			ca, caKey := newTestChainCertificate("kube-ca", true, time.Now().AddDate(1, 0, 0), nil, nil)
+			client, _ := newTestChainCertificate("system:admin", false, time.Now().AddDate(0, 0, 30), ca, caKey)
+			kubeconfig = strings.NewReplacer("$CA_DATA", kubeconfigCertificateData(ca), "$CLIENT_DATA", kubeconfigCertificateData(client)).Replace(kubeconfig)
+
+			found, err := DecodeKubeconfigCertificates([]byte(kubeconfig), logr.Discard())
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			decoded := []string{}
+			for _, entry := range found {
+				for _, cert := range entry.Certificates {
+					decoded = append(decoded, fmt.Sprintf("%s/%s/%s %s %s", entry.Reference.Context, entry.Reference.Cluster, entry.Reference.User, entry.Field, cert.Subject.CommonName))
+				}
+			}
+			Expect(decoded).To(Equal(expected))
+		},
+		table.Entry("embedded data referenced by a context", `
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://api.prod.example.com:6443
+    certificate-authority-data: $CA_DATA
+users:
+- name: admin
+  user:
+    client-certificate-data: $CLIENT_DATA
+contexts:
+- name: admin@prod
+  context:
+    cluster: prod
+    user: admin
+current-context: admin@prod
+`, []string{
+			"admin@prod/prod/ certificate-authority-data kube-ca",
+			"admin@prod//admin client-certificate-data system:admin",
+		}, false),
+		table.Entry("embedded data not referenced by any context", `
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://api.prod.example.com:6443
+    certificate-authority-data: $CA_DATA
+users:
+- name: admin
+  user:
+    client-certificate-data: $CLIENT_DATA
+`, []string{
+			"/prod/ certificate-authority-data kube-ca",
+			"//admin client-certificate-data system:admin",
+		}, false),
+		table.Entry("file references, which can not be read from the operator", `
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://api.prod.example.com:6443
+    certificate-authority: /etc/kubernetes/ca.crt
+users:
+- name: admin
+  user:
+    client-certificate: /etc/kubernetes/admin.crt
+    client-key: /etc/kubernetes/admin.key
+contexts:
+- name: admin@prod
+  context:
+    cluster: prod
+    user: admin
+`, []string{}, false),
+		table.Entry("embedded data that is not a certificate", `
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://api.prod.example.com:6443
+    certificate-authority-data: bm90IGEgY2VydGlmaWNhdGU=
+users:
+- name: admin
+  user:
+    client-certificate-data: $CLIENT_DATA
+contexts:
+- name: admin@prod
+  context:
+    cluster: prod
+    user: admin
+`, []string{
+			"admin@prod//admin client-certificate-data system:admin",
+		}, false),
+		table.Entry("a malformed kubeconfig", "apiVersion: v1\nkind: Config\nclusters: [\n", nil, true),
+		table.Entry("an empty kubeconfig", "", []string{}, false),
+	)
+})
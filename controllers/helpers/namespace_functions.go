@@ -0,0 +1,100 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+/*=====================================================================================
+| Namespace Pattern Helper Functions
+=====================================================================================*/
+
+// MatchNamespacePattern checks a namespace name against a pattern - a literal name, a `*` wildcard, a glob such as `team-*`, or a regular expression wrapped in slashes such as `/^team-(a|b)$/`
+func MatchNamespacePattern(pattern string, namespace string) (bool, error) {
+	pattern = strings.TrimSpace(pattern)
+
+	// Regular expressions are wrapped in slashes
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(namespace), nil
+	}
+
+	// Literal names and the single wildcard are both valid globs
+	return path.Match(pattern, namespace)
+}
+
+// FilterNamespaces returns the namespaces that match any of the include patterns and none of the exclude patterns
+// Include patterns prefixed with `!` are treated as excludes, and when the include patterns are only `!` negations every other namespace is included
+// An empty include list matches no namespaces, as it always has
+func FilterNamespaces(namespaces []string, includePatterns []string, excludePatterns []string) ([]string, error) {
+	var filtered []string
+	var includes []string
+	negated := false
+	excludes := append([]string{}, excludePatterns...)
+
+	for _, pattern := range includePatterns {
+		pattern = strings.TrimSpace(pattern)
+		if strings.HasPrefix(pattern, "!") {
+			excludes = append(excludes, strings.TrimPrefix(pattern, "!"))
+			negated = true
+		} else if pattern != "" {
+			includes = append(includes, pattern)
+		}
+	}
+	if len(includes) == 0 && negated {
+		includes = []string{"*"}
+	}
+
+	for _, namespace := range namespaces {
+		included, err := matchAnyNamespacePattern(includes, namespace)
+		if err != nil {
+			return filtered, err
+		}
+		if !included {
+			continue
+		}
+		excluded, err := matchAnyNamespacePattern(excludes, namespace)
+		if err != nil {
+			return filtered, err
+		}
+		if !excluded {
+			filtered = append(filtered, namespace)
+		}
+	}
+
+	return filtered, nil
+}
+
+// matchAnyNamespacePattern checks a namespace name against a slice of patterns
+func matchAnyNamespacePattern(patterns []string, namespace string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := MatchNamespacePattern(pattern, namespace)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FilterNamespaces", func() {
+	namespaces := []string{"default", "kube-system", "openshift", "openshift-etcd", "openshift-ingress", "team-a", "team-b", "team-c-dev"}
+
+	It("matches literal names", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"team-a", "default"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal([]string{"default", "team-a"}))
+	})
+
+	It("matches everything with a single wildcard", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"*"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal(namespaces))
+	})
+
+	It("matches globs", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"team-*"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal([]string{"team-a", "team-b", "team-c-dev"}))
+	})
+
+	It("matches regular expressions wrapped in slashes", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"/^team-[a-z]$/"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal([]string{"team-a", "team-b"}))
+	})
+
+	It("includes everything except negated patterns when only negations are given", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"!openshift-*", "!openshift", "!kube-*"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal([]string{"default", "team-a", "team-b", "team-c-dev"}))
+	})
+
+	It("matches nothing when no include patterns are given", func() {
+		filtered, err := FilterNamespaces(namespaces, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(BeEmpty())
+
+		filtered, err = FilterNamespaces(namespaces, []string{" "}, []string{"openshift*"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(BeEmpty())
+	})
+
+	It("removes excluded namespaces from the included ones", func() {
+		filtered, err := FilterNamespaces(namespaces, []string{"*"}, []string{"openshift*", "/-dev$/"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(Equal([]string{"default", "kube-system", "team-a", "team-b"}))
+	})
+
+	It("returns an error for an invalid regular expression", func() {
+		_, err := FilterNamespaces(namespaces, []string{"/team-(/"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
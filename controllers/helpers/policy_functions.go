@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
+/*=====================================================================================
+| Certificate Policy Helper Functions
+=====================================================================================*/
+
+const (
+	// PolicyRuleWeakKey flags RSA keys smaller than the policy minimum
+	PolicyRuleWeakKey = "weak-key"
+	// PolicyRuleWeakSignature flags SHA-1 and MD5 signatures
+	PolicyRuleWeakSignature = "weak-signature"
+	// PolicyRuleLongValidity flags leaf certificates valid for longer than the policy maximum
+	PolicyRuleLongValidity = "long-validity"
+	// PolicyRuleMissingSANs flags leaf certificates that only have a Common Name
+	PolicyRuleMissingSANs = "missing-sans"
+	// PolicyRuleRestrictedWildcard flags wildcard certificates in restricted namespaces
+	PolicyRuleRestrictedWildcard = "restricted-wildcard"
+)
+
+// EvaluateCertificatePolicy runs the policy checks against a certificate found in the namespace and returns any violations
+func EvaluateCertificatePolicy(cert *x509.Certificate, namespace string, policy configv1.CertificatePolicy) []configv1.PolicyViolation {
+	var violations []configv1.PolicyViolation
+
+	if rsaKey, ok := cert.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < policy.MinimumRSAKeySize {
+		violations = append(violations, configv1.PolicyViolation{Rule: PolicyRuleWeakKey, Severity: SeverityCritical, Message: fmt.Sprintf("RSA key is %d bits, under the minimum of %d", rsaKey.N.BitLen(), policy.MinimumRSAKeySize)})
+	}
+
+	// The signature on a self-signed root is never verified, so only check certificates signed by another
+	if isWeakSignatureAlgorithm(cert.SignatureAlgorithm) && !isSelfSigned(cert) {
+		violations = append(violations, configv1.PolicyViolation{Rule: PolicyRuleWeakSignature, Severity: SeverityCritical, Message: "Signed with " + cert.SignatureAlgorithm.String()})
+	}
+
+	// The validity period and SAN checks only apply to leaf certificates
+	if !cert.IsCA {
+		validityDays := int(cert.NotAfter.Sub(cert.NotBefore).Hours() / 24)
+		if policy.MaximumValidityDays > 0 && validityDays > policy.MaximumValidityDays {
+			violations = append(violations, configv1.PolicyViolation{Rule: PolicyRuleLongValidity, Severity: SeverityWarning, Message: fmt.Sprintf("Valid for %d days, over the maximum of %d", validityDays, policy.MaximumValidityDays)})
+		}
+		if len(cert.DNSNames) == 0 && len(cert.IPAddresses) == 0 && len(cert.URIs) == 0 && len(cert.EmailAddresses) == 0 {
+			violations = append(violations, configv1.PolicyViolation{Rule: PolicyRuleMissingSANs, Severity: SeverityWarning, Message: "No Subject Alternative Names, only the Common Name " + cert.Subject.CommonName})
+		}
+	}
+
+	if namespace != "" && isWildcardCertificate(cert) {
+		for _, pattern := range policy.RestrictedWildcardNamespaces {
+			matched, err := MatchNamespacePattern(pattern, namespace)
+			if err == nil && matched {
+				violations = append(violations, configv1.PolicyViolation{Rule: PolicyRuleRestrictedWildcard, Severity: SeverityWarning, Message: "Wildcard certificate in restricted namespace " + namespace})
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// isWeakSignatureAlgorithm checks if a signature algorithm uses SHA-1 or MD5
+func isWeakSignatureAlgorithm(algorithm x509.SignatureAlgorithm) bool {
+	switch algorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSelfSigned checks if a certificate was issued by its own subject
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer)
+}
+
+// isWildcardCertificate checks if the Common Name or any DNS Subject Alternative Name is a wildcard
+func isWildcardCertificate(cert *x509.Certificate) bool {
+	if strings.HasPrefix(cert.Subject.CommonName, "*.") {
+		return true
+	}
+	for _, dnsName := range cert.DNSNames {
+		if strings.HasPrefix(dnsName, "*.") {
+			return true
+		}
+	}
+	return false
+}
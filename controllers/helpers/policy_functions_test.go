@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// policyRules returns the rule names of the violations
+func policyRules(violations []configv1.PolicyViolation) []string {
+	var rules []string
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+var _ = Describe("EvaluateCertificatePolicy", func() {
+	policy := configv1.CertificatePolicy{MinimumRSAKeySize: 2048, MaximumValidityDays: 398, RestrictedWildcardNamespaces: []string{"prod-*"}}
+	notBefore := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	It("passes a healthy leaf certificate", func() {
+		cert := &x509.Certificate{
+			PublicKey:          &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 2047), E: 65537},
+			SignatureAlgorithm: x509.SHA256WithRSA,
+			RawSubject:         []byte("leaf"),
+			RawIssuer:          []byte("issuer"),
+			DNSNames:           []string{"app.example.com"},
+			NotBefore:          notBefore,
+			NotAfter:           notBefore.AddDate(0, 0, 90),
+		}
+		Expect(EvaluateCertificatePolicy(cert, "prod-a", policy)).To(BeEmpty())
+	})
+
+	It("flags weak keys, weak signatures, long validity, missing SANs, and restricted wildcards", func() {
+		cert := &x509.Certificate{
+			PublicKey:          &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 1023), E: 65537},
+			SignatureAlgorithm: x509.SHA1WithRSA,
+			RawSubject:         []byte("leaf"),
+			RawIssuer:          []byte("issuer"),
+			NotBefore:          notBefore,
+			NotAfter:           notBefore.AddDate(2, 0, 0),
+		}
+		cert.Subject.CommonName = "*.example.com"
+		Expect(policyRules(EvaluateCertificatePolicy(cert, "prod-a", policy))).To(Equal([]string{PolicyRuleWeakKey, PolicyRuleWeakSignature, PolicyRuleLongValidity, PolicyRuleMissingSANs, PolicyRuleRestrictedWildcard}))
+		Expect(policyRules(EvaluateCertificatePolicy(cert, "dev-a", policy))).NotTo(ContainElement(PolicyRuleRestrictedWildcard))
+	})
+
+	It("ignores the signature of self-signed roots and the leaf checks on CAs", func() {
+		cert := &x509.Certificate{
+			PublicKey:          &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 4095), E: 65537},
+			SignatureAlgorithm: x509.SHA1WithRSA,
+			RawSubject:         []byte("root"),
+			RawIssuer:          []byte("root"),
+			IsCA:               true,
+			NotBefore:          notBefore,
+			NotAfter:           notBefore.AddDate(20, 0, 0),
+		}
+		Expect(EvaluateCertificatePolicy(cert, "prod-a", policy)).To(BeEmpty())
+	})
+})
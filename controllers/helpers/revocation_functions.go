@@ -0,0 +1,396 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+)
+
+/*=====================================================================================
+| Revocation Helper Functions
+=====================================================================================*/
+
+// Revocation statuses
+const (
+	RevocationStatusGood    = "good"
+	RevocationStatusRevoked = "revoked"
+	RevocationStatusUnknown = "unknown"
+)
+
+// Revocation sources
+const (
+	RevocationSourceOCSP = "ocsp"
+	RevocationSourceCRL  = "crl"
+)
+
+// maxRevocationResponseSize caps the size of OCSP responses, CRLs, and issuer certificates that are read
+const maxRevocationResponseSize = 10 << 20
+
+// oidCRLReasonCode is the CRL entry extension holding the revocation reason
+var oidCRLReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// revocationReasons names the RFC 5280 revocation reason codes
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "keyCompromise",
+	ocsp.CACompromise:         "cACompromise",
+	ocsp.AffiliationChanged:   "affiliationChanged",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessationOfOperation",
+	ocsp.CertificateHold:      "certificateHold",
+	ocsp.RemoveFromCRL:        "removeFromCRL",
+	ocsp.PrivilegeWithdrawn:   "privilegeWithdrawn",
+	ocsp.AACompromise:         "aACompromise",
+}
+
+// RevocationResult is the revocation status of a certificate and where it came from
+type RevocationResult struct {
+	Status     string
+	Source     string
+	Responder  string
+	RevokedAt  time.Time
+	Reason     string
+	NextUpdate time.Time
+}
+
+// RevocationCache holds OCSP responses and CRLs until their nextUpdate, the issuers fetched from AIA URLs, and the endpoints that failed, so they are shared across scans
+type RevocationCache struct {
+	mutex    sync.Mutex
+	ocsp     map[string]RevocationResult
+	crls     map[string]*pkix.CertificateList
+	issuers  map[string]*x509.Certificate
+	failures map[string]revocationFailure
+}
+
+// revocationFailure is a failed request to a revocation endpoint, which is not retried until retryAt
+type revocationFailure struct {
+	err     error
+	retryAt time.Time
+}
+
+// NewRevocationCache returns an empty RevocationCache
+func NewRevocationCache() *RevocationCache {
+	return &RevocationCache{ocsp: map[string]RevocationResult{}, crls: map[string]*pkix.CertificateList{}, issuers: map[string]*x509.Certificate{}, failures: map[string]revocationFailure{}}
+}
+
+// RevocationChecker queries OCSP responders and CRL distribution points with its HTTP Client
+// Checks stop once the Deadline passes so an unreachable responder can not stall a scan, a zero Deadline does not limit them
+type RevocationChecker struct {
+	Client   *http.Client
+	Cache    *RevocationCache
+	Deadline time.Time
+}
+
+// NewRevocationHTTPClient returns an HTTP client for revocation checks sent through the proxy, or the proxy from the HTTP_PROXY environment variables when empty
+func NewRevocationHTTPClient(httpProxy string, timeout time.Duration) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if httpProxy != "" {
+		proxyURL, err := url.Parse(httpProxy)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, errors.New("invalid HTTP proxy " + httpProxy + ", expected a URL such as http://proxy.example.com:3128")
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Timeout: timeout, Transport: &http.Transport{Proxy: proxy}}, nil
+}
+
+// HasRevocationEndpoints checks if a certificate names an OCSP responder or CRL distribution point to check it against
+func HasRevocationEndpoints(cert *x509.Certificate) bool {
+	return len(cert.OCSPServer) > 0 || len(cert.CRLDistributionPoints) > 0
+}
+
+// errRevocationDeadlineExceeded is returned for the certificates left to check once the time allowed for revocation checks in a scan has passed
+var errRevocationDeadlineExceeded = errors.New("skipped, the time allowed for revocation checks in this scan has passed")
+
+// CheckRevocation returns the revocation status of a certificate, querying its OCSP responders first and falling back to its CRL distribution points
+// The issuer is looked for in the bundle the certificate was found in, then fetched from the AIA CA Issuers URL
+func (c *RevocationChecker) CheckRevocation(cert *x509.Certificate, bundle []*x509.Certificate, now time.Time) (RevocationResult, error) {
+	if !HasRevocationEndpoints(cert) {
+		return RevocationResult{Status: RevocationStatusUnknown}, errors.New("no OCSP responder or CRL distribution point")
+	}
+	if c.deadlineExceeded() {
+		return RevocationResult{Status: RevocationStatusUnknown}, errRevocationDeadlineExceeded
+	}
+	issuer, err := c.findIssuer(cert, bundle, now)
+	if err != nil {
+		return RevocationResult{Status: RevocationStatusUnknown}, err
+	}
+
+	var failures []string
+	for _, responder := range cert.OCSPServer {
+		result, err := c.checkOCSP(responder, cert, issuer, now)
+		if err == nil {
+			return result, nil
+		}
+		failures = append(failures, "OCSP "+responder+": "+err.Error())
+	}
+	for _, distributionPoint := range cert.CRLDistributionPoints {
+		result, err := c.checkCRL(distributionPoint, cert, issuer, now)
+		if err == nil {
+			return result, nil
+		}
+		failures = append(failures, "CRL "+distributionPoint+": "+err.Error())
+	}
+	return RevocationResult{Status: RevocationStatusUnknown}, errors.New(strings.Join(failures, "; "))
+}
+
+// checkOCSP queries an OCSP responder, reusing a cached response until its nextUpdate
+func (c *RevocationChecker) checkOCSP(responder string, cert *x509.Certificate, issuer *x509.Certificate, now time.Time) (RevocationResult, error) {
+	cacheKey := responder + "|" + certificateFingerprint(cert)
+	c.Cache.mutex.Lock()
+	cached, ok := c.Cache.ocsp[cacheKey]
+	c.Cache.mutex.Unlock()
+	if ok && now.Before(cached.NextUpdate) {
+		return cached, nil
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return RevocationResult{}, err
+	}
+	httpRequest, err := http.NewRequest(http.MethodPost, responder, bytes.NewReader(request))
+	if err != nil {
+		return RevocationResult{}, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/ocsp-request")
+	httpRequest.Header.Set("Accept", "application/ocsp-response")
+	body, err := c.fetch(httpRequest, now)
+	if err != nil {
+		return RevocationResult{}, err
+	}
+
+	response, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return RevocationResult{}, err
+	}
+	result := RevocationResult{Status: RevocationStatusGood, Source: RevocationSourceOCSP, Responder: responder, NextUpdate: response.NextUpdate}
+	switch response.Status {
+	case ocsp.Revoked:
+		result.Status = RevocationStatusRevoked
+		result.RevokedAt = response.RevokedAt
+		result.Reason = revocationReason(response.RevocationReason)
+	case ocsp.Unknown:
+		return RevocationResult{}, errors.New("the responder does not know the certificate")
+	}
+
+	// A response without a nextUpdate has no freshness window and is not cached
+	if !response.NextUpdate.IsZero() {
+		c.Cache.mutex.Lock()
+		pruneRevocationCache(c.Cache, now)
+		c.Cache.ocsp[cacheKey] = result
+		c.Cache.mutex.Unlock()
+	}
+	return result, nil
+}
+
+// checkCRL looks for the certificate in a CRL, reusing a cached CRL until its nextUpdate
+func (c *RevocationChecker) checkCRL(distributionPoint string, cert *x509.Certificate, issuer *x509.Certificate, now time.Time) (RevocationResult, error) {
+	c.Cache.mutex.Lock()
+	crl, ok := c.Cache.crls[distributionPoint]
+	c.Cache.mutex.Unlock()
+	if !ok || !now.Before(crl.TBSCertList.NextUpdate) {
+		if !strings.HasPrefix(distributionPoint, "http://") && !strings.HasPrefix(distributionPoint, "https://") {
+			return RevocationResult{}, errors.New("only HTTP distribution points are supported")
+		}
+		httpRequest, err := http.NewRequest(http.MethodGet, distributionPoint, nil)
+		if err != nil {
+			return RevocationResult{}, err
+		}
+		body, err := c.fetch(httpRequest, now)
+		if err != nil {
+			return RevocationResult{}, err
+		}
+		crl, err = x509.ParseCRL(body)
+		if err != nil {
+			return RevocationResult{}, err
+		}
+		if err := issuer.CheckCRLSignature(crl); err != nil {
+			return RevocationResult{}, err
+		}
+		if !crl.TBSCertList.NextUpdate.IsZero() {
+			c.Cache.mutex.Lock()
+			pruneRevocationCache(c.Cache, now)
+			c.Cache.crls[distributionPoint] = crl
+			c.Cache.mutex.Unlock()
+		}
+	}
+
+	result := RevocationResult{Status: RevocationStatusGood, Source: RevocationSourceCRL, Responder: distributionPoint, NextUpdate: crl.TBSCertList.NextUpdate}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+		result.Status = RevocationStatusRevoked
+		result.RevokedAt = revoked.RevocationTime
+		result.Reason = revocationReason(ocsp.Unspecified)
+		for _, extension := range revoked.Extensions {
+			var reasonCode asn1.Enumerated
+			if extension.Id.Equal(oidCRLReasonCode) {
+				if _, err := asn1.Unmarshal(extension.Value, &reasonCode); err == nil {
+					result.Reason = revocationReason(int(reasonCode))
+				}
+			}
+		}
+		break
+	}
+	return result, nil
+}
+
+// findIssuer returns the certificate that signed cert from the bundle, or from its AIA CA Issuers URL
+func (c *RevocationChecker) findIssuer(cert *x509.Certificate, bundle []*x509.Certificate, now time.Time) (*x509.Certificate, error) {
+	for _, candidate := range bundle {
+		if bytes.Equal(candidate.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate, nil
+		}
+	}
+
+	for _, issuerURL := range cert.IssuingCertificateURL {
+		c.Cache.mutex.Lock()
+		issuer, ok := c.Cache.issuers[issuerURL]
+		c.Cache.mutex.Unlock()
+		if !ok {
+			httpRequest, err := http.NewRequest(http.MethodGet, issuerURL, nil)
+			if err != nil {
+				continue
+			}
+			body, err := c.fetch(httpRequest, now)
+			if err != nil {
+				continue
+			}
+			// CA Issuers are usually DER, but some publish PEM
+			if block, _ := pem.Decode(body); block != nil {
+				body = block.Bytes
+			}
+			issuer, err = x509.ParseCertificate(body)
+			if err != nil {
+				continue
+			}
+			c.Cache.mutex.Lock()
+			c.Cache.issuers[issuerURL] = issuer
+			c.Cache.mutex.Unlock()
+		}
+		if cert.CheckSignatureFrom(issuer) == nil {
+			return issuer, nil
+		}
+	}
+	return nil, errors.New("issuer " + cert.Issuer.String() + " not found in the bundle or the AIA CA Issuers URL")
+}
+
+// deadlineExceeded checks if the time allowed for revocation checks has passed
+func (c *RevocationChecker) deadlineExceeded() bool {
+	return !c.Deadline.IsZero() && !time.Now().Before(c.Deadline)
+}
+
+// fetch sends the request and returns the body of a 200 response
+// An endpoint that fails is not sent another request until the failure backoff has passed, so an unreachable responder only costs one timeout
+func (c *RevocationChecker) fetch(httpRequest *http.Request, now time.Time) ([]byte, error) {
+	endpoint := httpRequest.URL.String()
+	c.Cache.mutex.Lock()
+	failure, failed := c.Cache.failures[endpoint]
+	c.Cache.mutex.Unlock()
+	if failed && now.Before(failure.retryAt) {
+		return nil, fmt.Errorf("%v (not retried until %s)", failure.err, failure.retryAt.UTC().Format(time.RFC3339))
+	}
+	if c.deadlineExceeded() {
+		return nil, errRevocationDeadlineExceeded
+	}
+
+	if !c.Deadline.IsZero() {
+		ctx, cancel := context.WithDeadline(httpRequest.Context(), c.Deadline)
+		defer cancel()
+		httpRequest = httpRequest.WithContext(ctx)
+	}
+	body, err := c.doFetch(httpRequest)
+
+	c.Cache.mutex.Lock()
+	if err != nil && !c.deadlineExceeded() {
+		c.Cache.failures[endpoint] = revocationFailure{err: err, retryAt: now.Add(defaults.RevocationFailureBackoff)}
+	} else if err == nil {
+		delete(c.Cache.failures, endpoint)
+	}
+	c.Cache.mutex.Unlock()
+	return body, err
+}
+
+// doFetch sends the request and returns the body of a 200 response
+func (c *RevocationChecker) doFetch(httpRequest *http.Request) ([]byte, error) {
+	response, err := c.Client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %s", response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, maxRevocationResponseSize))
+}
+
+// pruneRevocationCache drops the OCSP responses and CRLs past their nextUpdate and the failures past their backoff, the cache mutex must be held
+func pruneRevocationCache(cache *RevocationCache, now time.Time) {
+	for key, result := range cache.ocsp {
+		if !now.Before(result.NextUpdate) {
+			delete(cache.ocsp, key)
+		}
+	}
+	for key, crl := range cache.crls {
+		if !now.Before(crl.TBSCertList.NextUpdate) {
+			delete(cache.crls, key)
+		}
+	}
+	for key, failure := range cache.failures {
+		if !now.Before(failure.retryAt) {
+			delete(cache.failures, key)
+		}
+	}
+}
+
+// certificateFingerprint returns the hex SHA-256 fingerprint of a certificate
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// revocationReason names a revocation reason code
+func revocationReason(code int) string {
+	if reason, ok := revocationReasons[code]; ok {
+		return reason
+	}
+	return fmt.Sprintf("reason %d", code)
+}
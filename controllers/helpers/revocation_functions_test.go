@@ -0,0 +1,205 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ocsp"
+)
+
+// newTestRevocationLeaf creates a leaf certificate signed by the issuer that points at the OCSP responder and CRL distribution point
+func newTestRevocationLeaf(serial int64, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey, ocspServer []string, crlDistributionPoints []string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "app.example.com"},
+		DNSNames:              []string{"app.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		BasicConstraintsValid: true,
+		OCSPServer:            ocspServer,
+		CRLDistributionPoints: crlDistributionPoints,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return cert
+}
+
+var _ = Describe("RevocationChecker", func() {
+	const revokedSerial = 666
+	var issuer *x509.Certificate
+	var issuerKey *ecdsa.PrivateKey
+	var server *httptest.Server
+	var ocspRequests, crlRequests int32
+	var checker *RevocationChecker
+	now := time.Now()
+	revokedAt := now.Add(-24 * time.Hour).Truncate(time.Second)
+
+	BeforeEach(func() {
+		issuer, issuerKey = newTestChainCertificate("Test Issuing CA", true, now.AddDate(5, 0, 0), nil, nil)
+		issuer.KeyUsage |= x509.KeyUsageCRLSign
+		atomic.StoreInt32(&ocspRequests, 0)
+		atomic.StoreInt32(&crlRequests, 0)
+
+		// A local OCSP responder and CRL distribution point - serial 666 is revoked, the OCSP responder fails for /broken
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/ocsp":
+				atomic.AddInt32(&ocspRequests, 1)
+				body, _ := io.ReadAll(r.Body)
+				request, err := ocsp.ParseRequest(body)
+				Expect(err).NotTo(HaveOccurred())
+				template := ocsp.Response{Status: ocsp.Good, SerialNumber: request.SerialNumber, ThisUpdate: now.Add(-time.Hour), NextUpdate: now.Add(time.Hour)}
+				if request.SerialNumber.Int64() == revokedSerial {
+					template.Status, template.RevokedAt, template.RevocationReason = ocsp.Revoked, revokedAt, ocsp.KeyCompromise
+				}
+				response, err := ocsp.CreateResponse(issuer, issuer, template, issuerKey)
+				Expect(err).NotTo(HaveOccurred())
+				w.Header().Set("Content-Type", "application/ocsp-response")
+				_, _ = w.Write(response)
+			case "/crl":
+				atomic.AddInt32(&crlRequests, 1)
+				crl, err := issuer.CreateCRL(rand.Reader, issuerKey, []pkix.RevokedCertificate{{SerialNumber: big.NewInt(revokedSerial), RevocationTime: revokedAt}}, now.Add(-time.Hour), now.Add(time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				_, _ = w.Write(crl)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		client, err := NewRevocationHTTPClient("", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		checker = &RevocationChecker{Client: client, Cache: NewRevocationCache()}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reports good and revoked certificates from the OCSP responder", func() {
+		good := newTestRevocationLeaf(1, issuer, issuerKey, []string{server.URL + "/ocsp"}, nil)
+		result, err := checker.CheckRevocation(good, []*x509.Certificate{good, issuer}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusGood))
+		Expect(result.Source).To(Equal(RevocationSourceOCSP))
+
+		revoked := newTestRevocationLeaf(revokedSerial, issuer, issuerKey, []string{server.URL + "/ocsp"}, nil)
+		result, err = checker.CheckRevocation(revoked, []*x509.Certificate{revoked, issuer}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusRevoked))
+		Expect(result.RevokedAt.Equal(revokedAt)).To(BeTrue())
+		Expect(result.Reason).To(Equal("keyCompromise"))
+	})
+
+	It("caches OCSP responses until their nextUpdate", func() {
+		leaf := newTestRevocationLeaf(2, issuer, issuerKey, []string{server.URL + "/ocsp"}, nil)
+		for i := 0; i < 3; i++ {
+			_, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&ocspRequests)).To(Equal(int32(1)))
+
+		_, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now.Add(2*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&ocspRequests)).To(Equal(int32(2)))
+	})
+
+	It("falls back to the CRL when the OCSP responder fails", func() {
+		revoked := newTestRevocationLeaf(revokedSerial, issuer, issuerKey, []string{server.URL + "/broken"}, []string{server.URL + "/crl"})
+		result, err := checker.CheckRevocation(revoked, []*x509.Certificate{issuer}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusRevoked))
+		Expect(result.Source).To(Equal(RevocationSourceCRL))
+
+		good := newTestRevocationLeaf(3, issuer, issuerKey, nil, []string{server.URL + "/crl"})
+		result, err = checker.CheckRevocation(good, []*x509.Certificate{issuer}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusGood))
+		Expect(atomic.LoadInt32(&crlRequests)).To(Equal(int32(1)))
+	})
+
+	It("sends requests through the HTTP proxy", func() {
+		client, err := NewRevocationHTTPClient(server.URL, 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		checker.Client = client
+
+		// The responder host does not resolve, so the response can only come through the proxy
+		leaf := newTestRevocationLeaf(4, issuer, issuerKey, []string{"http://ocsp.example.invalid/ocsp"}, nil)
+		result, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusGood))
+		Expect(atomic.LoadInt32(&ocspRequests)).To(Equal(int32(1)))
+
+		_, err = NewRevocationHTTPClient("proxy.example.com", time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not retry a failed responder until the failure backoff has passed", func() {
+		var brokenRequests int32
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&brokenRequests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer broken.Close()
+
+		for serial := int64(10); serial < 13; serial++ {
+			leaf := newTestRevocationLeaf(serial, issuer, issuerKey, []string{broken.URL + "/ocsp"}, nil)
+			result, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Status).To(Equal(RevocationStatusUnknown))
+		}
+		Expect(atomic.LoadInt32(&brokenRequests)).To(Equal(int32(1)))
+
+		leaf := newTestRevocationLeaf(13, issuer, issuerKey, []string{broken.URL + "/ocsp"}, nil)
+		_, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now.Add(time.Hour))
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&brokenRequests)).To(Equal(int32(2)))
+	})
+
+	It("stops checking once the deadline for the scan has passed", func() {
+		checker.Deadline = time.Now().Add(-time.Second)
+		leaf := newTestRevocationLeaf(14, issuer, issuerKey, []string{server.URL + "/ocsp"}, []string{server.URL + "/crl"})
+		result, err := checker.CheckRevocation(leaf, []*x509.Certificate{issuer}, now)
+		Expect(err).To(MatchError(errRevocationDeadlineExceeded))
+		Expect(result.Status).To(Equal(RevocationStatusUnknown))
+		Expect(atomic.LoadInt32(&ocspRequests)).To(Equal(int32(0)))
+		Expect(atomic.LoadInt32(&crlRequests)).To(Equal(int32(0)))
+	})
+
+	It("reports an unknown status without a known issuer", func() {
+		leaf := newTestRevocationLeaf(5, issuer, issuerKey, []string{server.URL + "/ocsp"}, nil)
+		result, err := checker.CheckRevocation(leaf, nil, now)
+		Expect(err).To(HaveOccurred())
+		Expect(result.Status).To(Equal(RevocationStatusUnknown))
+	})
+})
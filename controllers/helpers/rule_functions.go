@@ -0,0 +1,185 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"google.golang.org/protobuf/proto"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
+/*=====================================================================================
+| Policy Rule Helper Functions
+=====================================================================================*/
+
+var (
+	ruleEnvOnce sync.Once
+	ruleEnv     *cel.Env
+	ruleEnvErr  error
+)
+
+// policyRuleEnv returns the CEL environment rules are compiled in, declaring the variables built by CertificateRuleVariables - the object the certificate was found in is `object` as `namespace` is a reserved word in CEL
+func policyRuleEnv() (*cel.Env, error) {
+	ruleEnvOnce.Do(func() {
+		ruleEnv, ruleEnvErr = cel.NewEnv(cel.Declarations(
+			decls.NewVar("cert", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("object", decls.NewMapType(decls.String, decls.String)),
+		))
+	})
+	return ruleEnv, ruleEnvErr
+}
+
+// PolicyRuleProgram is a policy rule with its compiled expression
+type PolicyRuleProgram struct {
+	Rule       configv1.PolicyRule
+	Expression cel.Program
+}
+
+// CompilePolicyRules compiles the CEL expression of each rule, returning the valid rules and an error for each invalid one
+func CompilePolicyRules(rules []configv1.PolicyRule) ([]PolicyRuleProgram, []error) {
+	var programs []PolicyRuleProgram
+	var errs []error
+	env, err := policyRuleEnv()
+	if err != nil {
+		return nil, []error{err}
+	}
+	for _, rule := range rules {
+		program, err := compileRuleExpression(env, rule.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %v", rule.Name, err))
+			continue
+		}
+		programs = append(programs, PolicyRuleProgram{Rule: rule, Expression: program})
+	}
+	return programs, errs
+}
+
+// compileRuleExpression type checks an expression and plans it with constant folding, which also compiles constant matches() patterns once
+func compileRuleExpression(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return nil, errors.New("expression must evaluate to a bool")
+	}
+	return env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+}
+
+// EvaluatePolicyRules runs the compiled rules against the rule variables, returning a violation for each rule that matched and an error for each that could not be evaluated
+func EvaluatePolicyRules(programs []PolicyRuleProgram, vars map[string]interface{}) ([]configv1.PolicyViolation, []error) {
+	var violations []configv1.PolicyViolation
+	var errs []error
+	for _, program := range programs {
+		out, _, err := program.Expression.Eval(vars)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %v", program.Rule.Name, err))
+			continue
+		}
+		matched, ok := out.(types.Bool)
+		if !ok {
+			errs = append(errs, fmt.Errorf("rule %s: expression evaluated to %v, not a bool", program.Rule.Name, out.Type()))
+			continue
+		}
+		if !matched {
+			continue
+		}
+		severity := program.Rule.Severity
+		if severity == "" {
+			severity = SeverityWarning
+		}
+		message := program.Rule.Message
+		if message == "" {
+			message = "Matched rule " + program.Rule.Name
+		}
+		violations = append(violations, configv1.PolicyViolation{Rule: program.Rule.Name, Severity: severity, Message: message})
+	}
+	return violations, errs
+}
+
+// CertificateRuleVariables builds the `cert` and `object` variables rules are evaluated against
+func CertificateRuleVariables(cert *x509.Certificate, namespace string, name string, kind string, dataKey string, now time.Time) map[string]interface{} {
+	_, _, daysRemaining := CertificateValidity(cert, now)
+
+	var ipAddresses []string
+	for _, ip := range cert.IPAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+	var uris []string
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	details := ParseCertificateDetails(cert)
+
+	return map[string]interface{}{
+		"cert": map[string]interface{}{
+			"cn":                 cert.Subject.CommonName,
+			"subject":            ruleNameVariables(cert.Subject),
+			"issuer":             ruleNameVariables(cert.Issuer),
+			"serialNumber":       details.SerialNumber,
+			"fingerprintSHA256":  details.FingerprintSHA256,
+			"dnsNames":           ruleStringList(cert.DNSNames),
+			"ipAddresses":        ruleStringList(ipAddresses),
+			"uris":               ruleStringList(uris),
+			"emailAddresses":     ruleStringList(cert.EmailAddresses),
+			"isCA":               cert.IsCA,
+			"keyAlgorithm":       details.PublicKeyAlgorithm,
+			"keySize":            int64(details.PublicKeySize),
+			"signatureAlgorithm": details.SignatureAlgorithm,
+			"keyUsage":           ruleStringList(details.KeyUsage),
+			"extKeyUsage":        ruleStringList(details.ExtendedKeyUsage),
+			"daysRemaining":      int64(daysRemaining),
+			"validityDays":       int64(cert.NotAfter.Sub(cert.NotBefore).Hours() / 24),
+		},
+		"object": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+			"kind":      kind,
+			"dataKey":   dataKey,
+		},
+	}
+}
+
+// ruleNameVariables builds the variables of a certificate subject or issuer
+func ruleNameVariables(name pkix.Name) map[string]interface{} {
+	return map[string]interface{}{
+		"cn": name.CommonName,
+		"dn": name.String(),
+		"o":  ruleStringList(name.Organization),
+		"ou": ruleStringList(name.OrganizationalUnit),
+		"c":  ruleStringList(name.Country),
+	}
+}
+
+// ruleStringList converts a string slice into a rule list
+func ruleStringList(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/x509"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EvaluatePolicyRules", func() {
+	It("reports a violation for each matching rule", func() {
+		programs, errs := CompilePolicyRules([]configv1.PolicyRule{
+			{Name: "small-keys", Expression: "cert.keySize < 3072", Severity: SeverityCritical, Message: "Keys must be at least 3072 bits"},
+			{Name: "prod-issuer", Expression: "object.namespace.startsWith('prod') && cert.issuer.cn != 'Internal CA'"},
+			{Name: "not-ca", Expression: "cert.isCA"},
+			{Name: "broken", Expression: "cert.keySize <"},
+		})
+		Expect(errs).To(HaveLen(1))
+		Expect(programs).To(HaveLen(3))
+
+		cert, err := x509.ParseCertificate(newTestTLSCertificate("app.example.com").Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		violations, errs := EvaluatePolicyRules(programs, CertificateRuleVariables(cert, "prod-a", "app-tls", "Secret", "tls.crt", time.Now()))
+		Expect(errs).To(BeEmpty())
+		Expect(violations).To(Equal([]configv1.PolicyViolation{
+			{Rule: "small-keys", Severity: SeverityCritical, Message: "Keys must be at least 3072 bits"},
+			{Rule: "prod-issuer", Severity: SeverityWarning, Message: "Matched rule prod-issuer"},
+		}))
+	})
+
+	It("supports the CEL macros, has(), and regular expressions", func() {
+		programs, errs := CompilePolicyRules([]configv1.PolicyRule{
+			{Name: "exists", Expression: "cert.dnsNames.exists(n, n.endsWith('.example.com'))"},
+			{Name: "all", Expression: "cert.dnsNames.all(n, n.matches('^[a-z.]+$'))"},
+			{Name: "has-cn", Expression: "has(cert.cn) && !has(cert.missing)"},
+			{Name: "no-wildcards", Expression: "cert.dnsNames.exists(n, n.startsWith('*.'))"},
+		})
+		Expect(errs).To(BeEmpty())
+
+		cert, err := x509.ParseCertificate(newTestTLSCertificate("app.example.com").Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		violations, errs := EvaluatePolicyRules(programs, CertificateRuleVariables(cert, "prod-a", "app-tls", "Secret", "tls.crt", time.Now()))
+		Expect(errs).To(BeEmpty())
+		var matched []string
+		for _, violation := range violations {
+			matched = append(matched, violation.Rule)
+		}
+		Expect(matched).To(Equal([]string{"exists", "all", "has-cn"}))
+	})
+
+	It("absorbs errors on either side of a decided logical operator", func() {
+		programs, errs := CompilePolicyRules([]configv1.PolicyRule{
+			{Name: "or", Expression: "cert.missing == 1 || object.namespace == 'prod-a'"},
+			{Name: "and", Expression: "cert.missing == 1 && object.namespace == 'dev'"},
+			{Name: "undecided", Expression: "cert.missing == 1 || object.namespace == 'dev'"},
+		})
+		Expect(errs).To(BeEmpty())
+
+		cert, err := x509.ParseCertificate(newTestTLSCertificate("app.example.com").Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		violations, errs := EvaluatePolicyRules(programs, CertificateRuleVariables(cert, "prod-a", "app-tls", "Secret", "tls.crt", time.Now()))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(HavePrefix("rule undecided:"))
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Rule).To(Equal("or"))
+	})
+
+	It("rejects undeclared variables and expressions that are not bools", func() {
+		programs, errs := CompilePolicyRules([]configv1.PolicyRule{
+			{Name: "undeclared", Expression: "certificate.keySize < 2048"},
+			{Name: "not-bool", Expression: "object.name + '-tls'"},
+			{Name: "bad-regex", Expression: "object.namespace.matches('[')"},
+		})
+		Expect(programs).To(BeEmpty())
+		Expect(errs).To(HaveLen(3))
+	})
+})
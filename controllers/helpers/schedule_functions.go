@@ -0,0 +1,104 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the IANA time zone database as the Operator image may not ship one
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+/*=====================================================================================
+| Report Schedule Helper Functions
+=====================================================================================*/
+
+// LoadTimeZone returns the IANA time zone location, defaulting to UTC when no time zone is given
+func LoadTimeZone(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(timeZone)
+}
+
+// NextScheduledReport returns the first time after the last report was sent that the cron schedule fires in the given time zone
+// The schedule is a standard 5 field cron expression, ie `0 9 * * 1-5`, or a descriptor such as `@daily`
+func NextScheduledReport(schedule string, timeZone string, lastReportSent time.Time) (time.Time, error) {
+	location, err := LoadTimeZone(timeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cronSchedule, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cronSchedule.Next(lastReportSent.In(location)), nil
+}
+
+// InQuietHours checks if a time falls in the quiet hours, given as `HH:MM-HH:MM` in the given time zone
+// Quiet hours that end before they start, ie `22:00-07:00`, wrap past midnight
+func InQuietHours(quietHours string, timeZone string, now time.Time) (bool, error) {
+	if quietHours == "" {
+		return false, nil
+	}
+	location, err := LoadTimeZone(timeZone)
+	if err != nil {
+		return false, err
+	}
+
+	quietRange := strings.Split(quietHours, "-")
+	if len(quietRange) != 2 {
+		return false, errors.New("quiet hours must be in the HH:MM-HH:MM format: " + quietHours)
+	}
+	start, err := parseMinuteOfDay(quietRange[0])
+	if err != nil {
+		return false, err
+	}
+	end, err := parseMinuteOfDay(quietRange[1])
+	if err != nil {
+		return false, err
+	}
+
+	localNow := now.In(location)
+	minute := localNow.Hour()*60 + localNow.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// parseMinuteOfDay converts a HH:MM time of day into minutes since midnight
+func parseMinuteOfDay(timeOfDay string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(timeOfDay), ":")
+	if len(parts) != 2 {
+		return 0, errors.New("invalid time of day, expected HH:MM: " + timeOfDay)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, errors.New("invalid hour in time of day: " + timeOfDay)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, errors.New("invalid minute in time of day: " + timeOfDay)
+	}
+	return hour*60 + minute, nil
+}
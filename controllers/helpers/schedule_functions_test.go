@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NextScheduledReport", func() {
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	It("lands on the next weekday at 09:00 in the time zone", func() {
+		// Friday 10:00 in New York
+		lastReportSent := time.Date(2021, time.October, 1, 10, 0, 0, 0, newYork)
+		next, err := NextScheduledReport("0 9 * * 1-5", "America/New_York", lastReportSent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next.Equal(time.Date(2021, time.October, 4, 9, 0, 0, 0, newYork))).To(BeTrue())
+	})
+
+	It("defaults to UTC and supports descriptors", func() {
+		lastReportSent := time.Date(2021, time.October, 1, 10, 0, 0, 0, time.UTC)
+		next, err := NextScheduledReport("@daily", "", lastReportSent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next.Equal(time.Date(2021, time.October, 2, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+	})
+
+	It("returns an error for an invalid schedule or time zone", func() {
+		_, err := NextScheduledReport("0 9 * *", "", time.Now())
+		Expect(err).To(HaveOccurred())
+		_, err = NextScheduledReport("0 9 * * *", "Mars/Olympus_Mons", time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("InQuietHours", func() {
+	It("matches quiet hours within a day", func() {
+		quiet, err := InQuietHours("12:00-13:30", "", time.Date(2021, time.October, 1, 13, 15, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quiet).To(BeTrue())
+
+		quiet, err = InQuietHours("12:00-13:30", "", time.Date(2021, time.October, 1, 13, 30, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quiet).To(BeFalse())
+	})
+
+	It("wraps quiet hours past midnight in the time zone", func() {
+		// 03:00 in Berlin
+		quiet, err := InQuietHours("22:00-07:00", "Europe/Berlin", time.Date(2021, time.October, 1, 1, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quiet).To(BeTrue())
+
+		// 12:00 in Berlin
+		quiet, err = InQuietHours("22:00-07:00", "Europe/Berlin", time.Date(2021, time.October, 1, 10, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quiet).To(BeFalse())
+	})
+
+	It("returns an error for invalid quiet hours", func() {
+		_, err := InQuietHours("22:00", "", time.Now())
+		Expect(err).To(HaveOccurred())
+		_, err = InQuietHours("25:00-07:00", "", time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"strings"
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+)
+
+/*=====================================================================================
+| Severity Helper Functions
+=====================================================================================*/
+
+// Severity levels, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	SeverityExpired  = "expired"
+	SeverityRevoked  = "revoked"
+)
+
+// severityRanks orders the severity levels, anything else ranks below info
+var severityRanks = map[string]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+	SeverityExpired:  4,
+	SeverityRevoked:  5,
+}
+
+// CertificateSeverity returns the severity of a certificate expiring at notAfter - `expired` once it has expired, otherwise the severity of the lowest daysOut threshold it expires within, or an empty string when it is outside all of them
+func CertificateSeverity(notAfter time.Time, now time.Time, severities []configv1.SeverityThreshold) string {
+	if notAfter.Before(now) {
+		return SeverityExpired
+	}
+
+	severity := ""
+	lowestDaysOut := -1
+	for _, threshold := range severities {
+		if notAfter.Before(now.AddDate(0, 0, threshold.DaysOut)) && (lowestDaysOut == -1 || threshold.DaysOut < lowestDaysOut) {
+			lowestDaysOut = threshold.DaysOut
+			severity = strings.ToLower(threshold.Severity)
+		}
+	}
+	return severity
+}
+
+// SeverityRank returns the rank of a severity level for comparisons, with 0 for unknown or empty levels
+func SeverityRank(severity string) int {
+	return severityRanks[strings.ToLower(severity)]
+}
+
+// MeetsMinimumSeverity checks if a severity is at or above the minimum severity, every severity meets an empty minimum
+func MeetsMinimumSeverity(severity string, minimumSeverity string) bool {
+	if minimumSeverity == "" {
+		return true
+	}
+	return SeverityRank(severity) >= SeverityRank(minimumSeverity)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"time"
+
+	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificateSeverity", func() {
+	now := time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC)
+	severities := []configv1.SeverityThreshold{
+		{DaysOut: 90, Severity: "info"},
+		{DaysOut: 7, Severity: "critical"},
+		{DaysOut: 30, Severity: "warning"},
+	}
+
+	It("maps to the severity of the lowest daysOut the certificate expires within", func() {
+		Expect(CertificateSeverity(now.AddDate(0, 0, 3), now, severities)).To(Equal(SeverityCritical))
+		Expect(CertificateSeverity(now.AddDate(0, 0, 20), now, severities)).To(Equal(SeverityWarning))
+		Expect(CertificateSeverity(now.AddDate(0, 0, 60), now, severities)).To(Equal(SeverityInfo))
+	})
+
+	It("has no severity outside every daysOut", func() {
+		Expect(CertificateSeverity(now.AddDate(1, 0, 0), now, severities)).To(BeEmpty())
+	})
+
+	It("treats certificates that have already expired as expired", func() {
+		Expect(CertificateSeverity(now.AddDate(0, -1, 0), now, severities)).To(Equal(SeverityExpired))
+	})
+})
+
+var _ = Describe("MeetsMinimumSeverity", func() {
+	It("compares severities by urgency", func() {
+		Expect(MeetsMinimumSeverity(SeverityCritical, SeverityWarning)).To(BeTrue())
+		Expect(MeetsMinimumSeverity(SeverityExpired, SeverityCritical)).To(BeTrue())
+		Expect(MeetsMinimumSeverity(SeverityInfo, SeverityWarning)).To(BeFalse())
+		Expect(MeetsMinimumSeverity("", SeverityInfo)).To(BeFalse())
+	})
+
+	It("lets every severity through without a minimum", func() {
+		Expect(MeetsMinimumSeverity("", "")).To(BeTrue())
+	})
+})
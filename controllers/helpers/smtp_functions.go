@@ -18,46 +18,112 @@ package helpers
 
 import (
 	"crypto/tls"
-	"log"
+	"crypto/x509"
+	"errors"
 	"net"
 	"strconv"
 	"time"
 
 	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+	dkim "github.com/toorop/go-dkim"
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
-// SendSMTPMail assembles everything needed to sent an email via go-simple-mail
-func SendSMTPMail(authType string, username string, password string, identity string, cramSecret string, useTLS *bool, useSTARTTLS *bool, to []string, from string, smtpServer string, subject string, textMessage string, htmlMessage string) {
+// SMTPAttachment is a file attached to an emailed message
+type SMTPAttachment struct {
+	// Name is the file name of the attachment
+	Name string
+	// MimeType is the content type of the attachment
+	MimeType string
+	// Data is the content of the attachment
+	Data []byte
+}
+
+// SMTPServerConfig provides the SMTP server connection, authentication, and TLS options used to send an email
+type SMTPServerConfig struct {
+	// Endpoint is the SMTP server with affixed port ie, smtp.example.com:25
+	Endpoint string
+	// AuthType can be either `none`, `plain`, `login`, `cram-md5`, or `xoauth2`
+	AuthType string
+	// Username is used by the `plain`, `login`, `cram-md5`, and `xoauth2` AuthTypes
+	Username string
+	// Password is used by the `plain` and `login` AuthTypes
+	Password string
+	// Identity is the optional identity used by the `plain` AuthType
+	Identity string
+	// CramSecret is the shared secret used by the `cram-md5` AuthType
+	CramSecret string
+	// OAuth2ClientID is the client ID used to fetch access tokens for the `xoauth2` AuthType
+	OAuth2ClientID string
+	// OAuth2ClientSecret is the client secret used to fetch access tokens for the `xoauth2` AuthType
+	OAuth2ClientSecret string
+	// OAuth2TokenURL is the token endpoint the `xoauth2` AuthType fetches access tokens from
+	OAuth2TokenURL string
+	// OAuth2Scopes are the optional scopes requested with the `xoauth2` access tokens
+	OAuth2Scopes []string
+	// DKIMDomain is the signing domain, the DKIM `d=` tag, used when a DKIMPrivateKey is provided
+	DKIMDomain string
+	// DKIMSelector is the DNS selector, the DKIM `s=` tag, of the public key published for the DKIMDomain
+	DKIMSelector string
+	// DKIMPrivateKey is an optional PEM encoded RSA key used to DKIM sign messages
+	DKIMPrivateKey []byte
+	// UseTLS enables the use of the TLS options
+	UseTLS *bool
+	// UseSTARTTLS upgrades the connection with STARTTLS
+	UseSTARTTLS *bool
+	// TLSServerName overrides the server name verified in the server certificate - defaults to the Endpoint hostname
+	TLSServerName string
+	// TLSMinVersion is the minimum TLS version to negotiate, can be `1.0`, `1.1`, `1.2`, or `1.3`
+	TLSMinVersion string
+	// TLSCABundle is an optional PEM encoded bundle of CAs to trust instead of the system roots
+	TLSCABundle []byte
+	// TLSClientCertificate is an optional PEM encoded client certificate for mutual TLS
+	TLSClientCertificate []byte
+	// TLSClientKey is the PEM encoded key of the TLSClientCertificate
+	TLSClientKey []byte
+}
+
+// SendSMTPMail assembles everything needed to sent an email via go-simple-mail and returns any connection or delivery error
+// The text and HTML messages are sent as a multipart/alternative message so text-only mail clients still get a readable report
+func SendSMTPMail(serverConfig SMTPServerConfig, to []string, from string, subject string, textMessage string, htmlMessage string, attachments []SMTPAttachment) error {
 
 	// Create a new SMTP Client
 	server := mail.NewSMTPClient()
 
 	// Set up the Server Connection
-	smtpHost, smtpPort, _ := net.SplitHostPort(smtpServer)
+	smtpHost, smtpPort, err := net.SplitHostPort(serverConfig.Endpoint)
+	if err != nil {
+		return err
+	}
 	server.Host = smtpHost
-	server.Port, _ = strconv.Atoi(smtpPort)
+	server.Port, err = strconv.Atoi(smtpPort)
+	if err != nil {
+		return err
+	}
 
 	// Set Authentication
-	switch authType {
+	switch serverConfig.AuthType {
 	case "none":
 		server.Authentication = mail.AuthNone
 	case "cram-md5":
 		server.Authentication = mail.AuthCRAMMD5
-		server.Username = username
-		server.Password = cramSecret
+		server.Username = serverConfig.Username
+		server.Password = serverConfig.CramSecret
 	case "login":
 		server.Authentication = mail.AuthLogin
-		server.Username = username
-		server.Password = password
+		server.Username = serverConfig.Username
+		server.Password = serverConfig.Password
+	case "xoauth2":
+		// XOAUTH2 is sent over net/smtp as go-simple-mail does not support it
+		server.Authentication = mail.AuthNone
 	case "plain":
 		server.Authentication = mail.AuthPlain
-		server.Username = username
-		server.Password = password
+		server.Username = serverConfig.Username
+		server.Password = serverConfig.Password
 	default:
 		server.Authentication = mail.AuthPlain
-		server.Username = username
-		server.Password = password
+		server.Username = serverConfig.Username
+		server.Password = serverConfig.Password
 	}
 
 	// Set other server connection variables
@@ -69,21 +135,107 @@ func SendSMTPMail(authType string, username string, password string, identity st
 	server.SendTimeout = 10 * time.Second
 
 	// Set STARTTLS config
-	if *useSTARTTLS {
+	useSTARTTLS := serverConfig.UseSTARTTLS != nil && *serverConfig.UseSTARTTLS
+	useTLS := serverConfig.UseTLS != nil && *serverConfig.UseTLS
+	if useSTARTTLS {
 		server.Encryption = mail.EncryptionSTARTTLS
 	}
-	// Set TLSConfig to provide custom TLS configuration. For example, to skip TLS verification (useful for testing):
-	if *useTLS {
-		server.TLSConfig = &tls.Config{InsecureSkipVerify: false}
+	// Set TLSConfig to provide custom TLS configuration, such as a private CA, client certificates, or a minimum version
+	if useTLS || useSTARTTLS {
+		serverName := serverConfig.TLSServerName
+		if serverName == "" {
+			serverName = smtpHost
+		}
+		server.TLSConfig, err = NewSMTPTLSConfig(serverName, serverConfig.TLSMinVersion, serverConfig.TLSCABundle, serverConfig.TLSClientCertificate, serverConfig.TLSClientKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set up new email message
+	email := NewSMTPMessage(to, from, subject, textMessage, htmlMessage, attachments)
+
+	// DKIM sign the headers and body of the message
+	if len(serverConfig.DKIMPrivateKey) > 0 {
+		email.SetDkim(NewSMTPDKIMOptions(serverConfig.DKIMDomain, serverConfig.DKIMSelector, serverConfig.DKIMPrivateKey))
+	}
+
+	// Send message
+	// always check error before send (y tho?)
+	if email.Error != nil {
+		return email.Error
+	}
+
+	// Send XOAUTH2 authenticated messages with an access token
+	if serverConfig.AuthType == "xoauth2" {
+		message := email.DkimMsg
+		if message == "" {
+			message = email.GetMessage()
+		}
+		return sendXOAUTH2Mail(server.Host, server.Port, server.TLSConfig, useTLS, useSTARTTLS, serverConfig, from, to, message)
 	}
 
 	// Create SMTP client
 	smtpClient, err := server.Connect()
 	if err != nil {
-		log.Printf("%v\n", err)
+		return err
 	}
 
-	// Set up new email message
+	// Call Send and pass the client
+	return email.Send(smtpClient)
+}
+
+// NewSMTPTLSConfig builds the TLS configuration used to connect to the SMTP server
+// The CA bundle replaces the system roots when provided, and the client certificate and key are presented for mutual TLS
+func NewSMTPTLSConfig(serverName string, minVersion string, caBundle []byte, clientCertificate []byte, clientKey []byte) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: serverName}
+
+	switch minVersion {
+	case "":
+	case "1.0":
+		tlsConfig.MinVersion = tls.VersionTLS10
+	case "1.1":
+		tlsConfig.MinVersion = tls.VersionTLS11
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.New("unsupported minimum TLS version: " + minVersion)
+	}
+
+	if len(caBundle) > 0 {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("no PEM encoded certificates found in the SMTP CA bundle")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if len(clientCertificate) > 0 || len(clientKey) > 0 {
+		keyPair, err := tls.X509KeyPair(clientCertificate, clientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
+
+	return tlsConfig, nil
+}
+
+// NewSMTPDKIMOptions returns the DKIM signature options for the report messages, signing the addressing, subject, date, and MIME headers along with the body
+func NewSMTPDKIMOptions(domain string, selector string, privateKey []byte) dkim.SigOptions {
+	options := dkim.NewSigOptions()
+	options.Domain = domain
+	options.Selector = selector
+	options.PrivateKey = privateKey
+	options.Canonicalization = "relaxed/relaxed"
+	options.Headers = []string{"from", "to", "subject", "date", "mime-version", "content-type"}
+	return options
+}
+
+// NewSMTPMessage builds the email message, with the text and HTML messages as a multipart/alternative body and any attachments
+func NewSMTPMessage(to []string, from string, subject string, textMessage string, htmlMessage string, attachments []SMTPAttachment) *mail.Email {
 	email := mail.NewMSG()
 	emailSubject := defaults.SetDefaultString(defaults.SMTPMessageSubject, subject)
 
@@ -98,27 +250,20 @@ func SendSMTPMail(authType string, username string, password string, identity st
 
 	// Set additional message headers
 
-	// Set the message body
-	if htmlMessage != "" {
-		email.SetBody(mail.TextHTML, htmlMessage)
-	}
-	/*
-		if textMessage != "" {
-			email.AddAlternative(mail.TextPlain, textMessage)
+	// Set the message body, the plain text part goes first as the last alternative is the preferred one
+	if textMessage != "" {
+		email.SetBody(mail.TextPlain, textMessage)
+		if htmlMessage != "" {
+			email.AddAlternative(mail.TextHTML, htmlMessage)
 		}
-	*/
-
-	// Send message
-	// always check error before send (y tho?)
-	if email.Error != nil {
-		log.Printf("%v\n", email.Error)
+	} else if htmlMessage != "" {
+		email.SetBody(mail.TextHTML, htmlMessage)
 	}
 
-	// Call Send and pass the client
-	err = email.Send(smtpClient)
-	if err != nil {
-		log.Println(err)
+	// Attach any exported files
+	for _, attachment := range attachments {
+		email.Attach(&mail.File{Name: attachment.Name, MimeType: attachment.MimeType, Data: attachment.Data})
 	}
-	// Email sent!
 
+	return email
 }
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendSMTPMail", func() {
+	useTLS := false
+	useSTARTTLS := false
+
+	It("returns an error for an SMTP endpoint without a port", func() {
+		err := SendSMTPMail(SMTPServerConfig{Endpoint: "smtp.example.com", AuthType: "none", UseTLS: &useTLS, UseSTARTTLS: &useSTARTTLS}, []string{"to@example.com"}, "from@example.com", "", "text", "<p>html</p>", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error when the SMTP server cannot be reached", func() {
+		// Grab a free port and close it so nothing is listening
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		address := listener.Addr().String()
+		listener.Close()
+
+		err = SendSMTPMail(SMTPServerConfig{Endpoint: address, AuthType: "none", UseTLS: &useTLS, UseSTARTTLS: &useSTARTTLS}, []string{"to@example.com"}, "from@example.com", "", "text", "<p>html</p>", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("NewSMTPTLSConfig", func() {
+	clientCert := newTestTLSCertificate("client.example.com")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]})
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(clientCert.PrivateKey)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	It("sets the server name and minimum version", func() {
+		tlsConfig, err := NewSMTPTLSConfig("relay.example.com", "1.2", nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tlsConfig.ServerName).To(Equal("relay.example.com"))
+		Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(tlsConfig.RootCAs).To(BeNil())
+		Expect(tlsConfig.InsecureSkipVerify).To(BeFalse())
+	})
+
+	It("returns an error for an unsupported minimum version", func() {
+		_, err := NewSMTPTLSConfig("relay.example.com", "1.4", nil, nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("trusts the CA bundle and presents the client certificate", func() {
+		tlsConfig, err := NewSMTPTLSConfig("relay.example.com", "", certPEM, certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tlsConfig.RootCAs).NotTo(BeNil())
+		Expect(tlsConfig.Certificates).To(HaveLen(1))
+	})
+
+	It("returns an error for a CA bundle without certificates", func() {
+		_, err := NewSMTPTLSConfig("relay.example.com", "", []byte("not a certificate"), nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error for a client certificate without its key", func() {
+		_, err := NewSMTPTLSConfig("relay.example.com", "", nil, certPEM, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("NewSMTPMessage", func() {
+	It("sends the text and HTML reports as a multipart/alternative message", func() {
+		message := NewSMTPMessage([]string{"to@example.com"}, "from@example.com", "Report", "plain text report", "<p>html report</p>", nil).GetMessage()
+		Expect(message).To(ContainSubstring("multipart/alternative"))
+		Expect(message).To(ContainSubstring("text/plain"))
+		Expect(message).To(ContainSubstring("text/html"))
+		Expect(strings.Index(message, "text/plain")).To(BeNumerically("<", strings.Index(message, "text/html")))
+	})
+
+	It("attaches the exported files", func() {
+		attachments := []SMTPAttachment{
+			{Name: "certificates-at-risk.csv", MimeType: "text/csv", Data: []byte("Name\nmy-cert\n")},
+			{Name: "certificates-at-risk.json", MimeType: "application/json", Data: []byte("[]")},
+		}
+		message := NewSMTPMessage([]string{"to@example.com"}, "from@example.com", "Report", "plain text report", "<p>html report</p>", attachments).GetMessage()
+		Expect(message).To(ContainSubstring("multipart/mixed"))
+		Expect(message).To(ContainSubstring("certificates-at-risk.csv"))
+		Expect(message).To(ContainSubstring("certificates-at-risk.json"))
+	})
+})
+
+var _ = Describe("NewSMTPDKIMOptions", func() {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	It("signs the headers and body of the report", func() {
+		email := NewSMTPMessage([]string{"to@example.com"}, "sentinel@example.com", "Report", "plain text report", "<p>html report</p>", nil)
+		email.SetDkim(NewSMTPDKIMOptions("example.com", "sentinel", rsaKeyPEM))
+		Expect(email.Error).NotTo(HaveOccurred())
+		Expect(email.DkimMsg).To(HavePrefix("DKIM-Signature: "))
+		Expect(email.DkimMsg).To(ContainSubstring("d=example.com"))
+		Expect(email.DkimMsg).To(ContainSubstring("s=sentinel"))
+		Expect(email.DkimMsg).To(ContainSubstring("h=from:to:subject:date:"))
+		Expect(email.DkimMsg).To(ContainSubstring("bh="))
+	})
+
+	It("returns an error for an invalid private key", func() {
+		useTLS := false
+		useSTARTTLS := false
+		serverConfig := SMTPServerConfig{
+			Endpoint:       "127.0.0.1:25",
+			AuthType:       "none",
+			UseTLS:         &useTLS,
+			UseSTARTTLS:    &useSTARTTLS,
+			DKIMDomain:     "example.com",
+			DKIMSelector:   "sentinel",
+			DKIMPrivateKey: []byte("not a key"),
+		}
+		err := SendSMTPMail(serverConfig, []string{"to@example.com"}, "sentinel@example.com", "Report", "text", "<p>html</p>", nil)
+		Expect(err).To(MatchError(ContainSubstring("dkim")))
+	})
+})
+
+var _ = Describe("RetryBackoff", func() {
+	It("doubles the delay after each failure", func() {
+		Expect(RetryBackoff(1, time.Minute, time.Hour)).To(Equal(time.Minute))
+		Expect(RetryBackoff(2, time.Minute, time.Hour)).To(Equal(2 * time.Minute))
+		Expect(RetryBackoff(4, time.Minute, time.Hour)).To(Equal(8 * time.Minute))
+	})
+
+	It("caps the delay", func() {
+		Expect(RetryBackoff(10, time.Minute, time.Hour)).To(Equal(time.Hour))
+		Expect(RetryBackoff(1000, time.Minute, time.Hour)).To(Equal(time.Hour))
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	defaults "github.com/kenmoini/certificate-sentinel-operator/controllers/defaults"
+)
+
+/*=====================================================================================
+| SMTP XOAUTH2 Helper Functions
+=====================================================================================*/
+
+// xoauth2TokenSources caches a refreshing token source per OAuth2 client so access tokens are reused until they expire
+var xoauth2TokenSources = map[string]xoauth2TokenSource{}
+var xoauth2TokenSourcesLock sync.Mutex
+
+// xoauth2TokenSource is a cached token source along with a hash of the client secret it was created with
+type xoauth2TokenSource struct {
+	secretHash  string
+	tokenSource oauth2.TokenSource
+}
+
+// xoauth2Auth implements the XOAUTH2 SASL mechanism used by Microsoft 365 and Google Workspace
+type xoauth2Auth struct {
+	username    string
+	accessToken string
+}
+
+// Start begins the XOAUTH2 exchange by sending the username and bearer token as the initial response
+// Like smtp.PlainAuth it refuses to send the token over an unencrypted connection to anything but localhost
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return "", nil, errors.New("refusing to send the XOAUTH2 access token over an unencrypted connection, enable smtp_use_ssl or smtp_use_starttls")
+	}
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.accessToken + "\x01\x01"), nil
+}
+
+// Next answers a failed XOAUTH2 exchange with an empty response so the server returns the error
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// GetXOAUTH2AccessToken returns an access token for the client credentials, fetching a new one from the token endpoint when the cached one has expired
+func GetXOAUTH2AccessToken(clientID string, clientSecret string, tokenURL string, scopes []string) (string, error) {
+	if clientID == "" || clientSecret == "" || tokenURL == "" {
+		return "", errors.New("XOAUTH2 needs a client_id, client_secret, and token_url")
+	}
+
+	// Keep a hash of the secret so a rotated client_secret replaces the token source of the old one rather than reusing it
+	secretSum := sha256.Sum256([]byte(clientSecret))
+	secretHash := hex.EncodeToString(secretSum[:])
+	cacheKey := tokenURL + "|" + clientID + "|" + strings.Join(scopes, " ")
+	xoauth2TokenSourcesLock.Lock()
+	cached, ok := xoauth2TokenSources[cacheKey]
+	tokenSource := cached.tokenSource
+	if !ok || cached.secretHash != secretHash {
+		clientConfig := &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       scopes,
+		}
+		// The token source keeps this context for every refresh, so the timeout applies to each token request
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: time.Duration(defaults.XOAUTH2TokenTimeout) * time.Second})
+		tokenSource = clientConfig.TokenSource(ctx)
+		// Start over rather than grow without bound, the dropped clients fetch a new access token on their next report
+		if !ok && len(xoauth2TokenSources) >= defaults.XOAUTH2TokenSourcesMax {
+			xoauth2TokenSources = map[string]xoauth2TokenSource{}
+		}
+		xoauth2TokenSources[cacheKey] = xoauth2TokenSource{secretHash: secretHash, tokenSource: tokenSource}
+	}
+	xoauth2TokenSourcesLock.Unlock()
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// sendXOAUTH2Mail delivers an assembled message over net/smtp, authenticating with an XOAUTH2 access token
+// The connection is wrapped in TLS straight away when TLS is used without STARTTLS
+func sendXOAUTH2Mail(host string, port int, tlsConfig *tls.Config, useTLS bool, useSTARTTLS bool, serverConfig SMTPServerConfig, from string, to []string, message string) error {
+	accessToken, err := GetXOAUTH2AccessToken(serverConfig.OAuth2ClientID, serverConfig.OAuth2ClientSecret, serverConfig.OAuth2TokenURL, serverConfig.OAuth2Scopes)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: host}
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if useTLS && !useSTARTTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		return err
+	}
+
+	smtpClient, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer smtpClient.Close()
+
+	if err = smtpClient.Hello("localhost"); err != nil {
+		return err
+	}
+	if useSTARTTLS {
+		if err = smtpClient.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if err = smtpClient.Auth(&xoauth2Auth{username: serverConfig.Username, accessToken: accessToken}); err != nil {
+		return err
+	}
+
+	if err = smtpClient.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err = smtpClient.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	dataWriter, err := smtpClient.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = dataWriter.Write([]byte(message)); err != nil {
+		return err
+	}
+	if err = dataWriter.Close(); err != nil {
+		return err
+	}
+
+	return smtpClient.Quit()
+}
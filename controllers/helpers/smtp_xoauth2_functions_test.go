@@ -0,0 +1,236 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// startTestSMTPServer accepts a single connection and speaks enough SMTP to accept one XOAUTH2 authenticated message, over implicit TLS when given a tlsConfig
+func startTestSMTPServer(tlsConfig *tls.Config, acceptedToken string, authResponses chan<- string, messages chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	go func() {
+		defer GinkgoRecover()
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		_, _ = io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				_, _ = io.WriteString(conn, "250-mail.example.com\r\n250 AUTH XOAUTH2\r\n")
+			case strings.HasPrefix(command, "AUTH XOAUTH2 "):
+				decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(command, "AUTH XOAUTH2 "))
+				authResponses <- string(decoded)
+				if strings.Contains(string(decoded), "auth=Bearer "+acceptedToken+"\x01") {
+					_, _ = io.WriteString(conn, "235 2.7.0 Accepted\r\n")
+				} else {
+					_, _ = io.WriteString(conn, "334 eyJzdGF0dXMiOiI0MDEifQ==\r\n")
+					_, _ = reader.ReadString('\n')
+					_, _ = io.WriteString(conn, "535 5.7.8 Authentication failed\r\n")
+				}
+			case strings.HasPrefix(command, "MAIL FROM"), strings.HasPrefix(command, "RCPT TO"):
+				_, _ = io.WriteString(conn, "250 OK\r\n")
+			case command == "DATA":
+				_, _ = io.WriteString(conn, "354 Go ahead\r\n")
+				var message strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					message.WriteString(dataLine)
+				}
+				messages <- message.String()
+				_, _ = io.WriteString(conn, "250 Queued\r\n")
+			case command == "QUIT":
+				_, _ = io.WriteString(conn, "221 Bye\r\n")
+				return
+			default:
+				_, _ = io.WriteString(conn, "502 Unknown command\r\n")
+			}
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+var _ = Describe("XOAUTH2", func() {
+	useTLS := false
+	useSTARTTLS := false
+	var tokenRequests int
+	var tokenServer *httptest.Server
+
+	BeforeEach(func() {
+		tokenRequests = 0
+		tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenRequests++
+			Expect(r.ParseForm()).To(Succeed())
+			if r.Form.Get("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"access_token":"test-access-token","token_type":"Bearer","expires_in":3600}`)
+		}))
+	})
+
+	AfterEach(func() {
+		tokenServer.Close()
+	})
+
+	It("fetches an access token once and reuses it until it expires", func() {
+		token, err := GetXOAUTH2AccessToken("client-id", "client-secret", tokenServer.URL, []string{"https://outlook.office365.com/.default"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("test-access-token"))
+
+		token, err = GetXOAUTH2AccessToken("client-id", "client-secret", tokenServer.URL, []string{"https://outlook.office365.com/.default"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("test-access-token"))
+		Expect(tokenRequests).To(Equal(1))
+	})
+
+	It("fetches a new access token when the client secret is rotated", func() {
+		_, err := GetXOAUTH2AccessToken("client-id", "client-secret", tokenServer.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = GetXOAUTH2AccessToken("client-id", "rotated-client-secret", tokenServer.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tokenRequests).To(Equal(2))
+	})
+
+	It("replaces the token source of a rotated client secret rather than caching every secret", func() {
+		cachedClients := len(xoauth2TokenSources)
+		for i := 0; i < 3; i++ {
+			_, err := GetXOAUTH2AccessToken("rotating-client-id", fmt.Sprintf("client-secret-%d", i), tokenServer.URL, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tokenRequests).To(Equal(3))
+		Expect(xoauth2TokenSources).To(HaveLen(cachedClients + 1))
+	})
+
+	It("refuses to send the access token over an unencrypted connection", func() {
+		auth := &xoauth2Auth{username: "sentinel@example.com", accessToken: "test-access-token"}
+		_, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: false})
+		Expect(err).To(MatchError(ContainSubstring("unencrypted connection")))
+
+		_, _, err = auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = auth.Start(&smtp.ServerInfo{Name: "localhost", TLS: false})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns an error without client credentials", func() {
+		_, err := GetXOAUTH2AccessToken("", "", tokenServer.URL, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("authenticates with the access token and delivers the message", func() {
+		authResponses := make(chan string, 1)
+		messages := make(chan string, 1)
+		address := startTestSMTPServer(nil, "test-access-token", authResponses, messages)
+
+		serverConfig := SMTPServerConfig{
+			Endpoint:           address,
+			AuthType:           "xoauth2",
+			Username:           "sentinel@example.com",
+			OAuth2ClientID:     "client-id",
+			OAuth2ClientSecret: "client-secret",
+			OAuth2TokenURL:     tokenServer.URL,
+			UseTLS:             &useTLS,
+			UseSTARTTLS:        &useSTARTTLS,
+		}
+		err := SendSMTPMail(serverConfig, []string{"to@example.com"}, "sentinel@example.com", "Report", "plain text report", "<p>html report</p>", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authResponses).To(Equal("user=sentinel@example.com\x01auth=Bearer test-access-token\x01\x01"))
+		Expect(<-messages).To(ContainSubstring("plain text report"))
+	})
+
+	It("delivers the message over implicit TLS when STARTTLS is disabled", func() {
+		certServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		serverTLSConfig := &tls.Config{Certificates: certServer.TLS.Certificates}
+		caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certServer.Certificate().Raw})
+		certServer.Close()
+
+		authResponses := make(chan string, 1)
+		messages := make(chan string, 1)
+		address := startTestSMTPServer(serverTLSConfig, "test-access-token", authResponses, messages)
+
+		useImplicitTLS := true
+		serverConfig := SMTPServerConfig{
+			Endpoint:           address,
+			AuthType:           "xoauth2",
+			Username:           "sentinel@example.com",
+			OAuth2ClientID:     "client-id",
+			OAuth2ClientSecret: "client-secret",
+			OAuth2TokenURL:     tokenServer.URL,
+			UseTLS:             &useImplicitTLS,
+			UseSTARTTLS:        &useSTARTTLS,
+			TLSCABundle:        caBundle,
+		}
+		err := SendSMTPMail(serverConfig, []string{"to@example.com"}, "sentinel@example.com", "Report", "plain text report", "<p>html report</p>", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authResponses).To(Equal("user=sentinel@example.com\x01auth=Bearer test-access-token\x01\x01"))
+		Expect(<-messages).To(ContainSubstring("plain text report"))
+	})
+
+	It("returns an error when the SMTP server rejects the access token", func() {
+		authResponses := make(chan string, 1)
+		messages := make(chan string, 1)
+		address := startTestSMTPServer(nil, "another-access-token", authResponses, messages)
+
+		serverConfig := SMTPServerConfig{
+			Endpoint:           address,
+			AuthType:           "xoauth2",
+			Username:           "sentinel@example.com",
+			OAuth2ClientID:     "client-id",
+			OAuth2ClientSecret: "client-secret",
+			OAuth2TokenURL:     tokenServer.URL,
+			UseTLS:             &useTLS,
+			UseSTARTTLS:        &useSTARTTLS,
+		}
+		err := SendSMTPMail(serverConfig, []string{"to@example.com"}, "sentinel@example.com", "Report", "plain text report", "<p>html report</p>", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
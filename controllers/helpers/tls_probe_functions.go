@@ -0,0 +1,263 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+/*=====================================================================================
+| TLS Endpoint Probing Helper Functions
+=====================================================================================*/
+
+// ldapStartTLSOID is the LDAPv3 extended operation that upgrades the connection to TLS
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// postgresSSLRequestCode is the magic number sent in the Postgres SSLRequest startup packet
+const postgresSSLRequestCode = 80877103
+
+// ProbeTLSEndpoint dials a host:port, optionally negotiates STARTTLS for `smtp`, `ldap`, or `postgres`, and returns the certificate chain presented by the server
+func ProbeTLSEndpoint(address string, serverName string, startTLS string, timeout time.Duration) ([]*x509.Certificate, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The deadline covers the STARTTLS negotiation and the TLS handshake
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+
+	// Negotiate the in-protocol upgrade to TLS
+	switch strings.ToLower(startTLS) {
+	case "":
+	case "smtp":
+		err = negotiateSMTPStartTLS(conn)
+	case "ldap":
+		err = negotiateLDAPStartTLS(conn)
+	case "postgres", "postgresql":
+		err = negotiatePostgresStartTLS(conn)
+	default:
+		err = errors.New("unsupported STARTTLS protocol: " + startTLS)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Default the SNI server name to the dialed hostname, IP addresses are not sent as SNI
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && net.ParseIP(host) == nil {
+			serverName = host
+		}
+	}
+
+	// Verification is skipped as the point is to capture whatever chain is served, trusted or not
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	err = tlsConn.Handshake()
+	if err != nil {
+		return nil, err
+	}
+
+	peerCertificates := tlsConn.ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		return nil, errors.New("no certificates presented by " + address)
+	}
+	return peerCertificates, nil
+}
+
+// negotiateSMTPStartTLS reads the SMTP greeting, sends EHLO, and issues STARTTLS
+func negotiateSMTPStartTLS(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+
+	// Read the server greeting
+	if _, err := readSMTPResponse(reader, "220"); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(conn, "EHLO certificate-sentinel\r\n"); err != nil {
+		return err
+	}
+	ehloResponse, err := readSMTPResponse(reader, "250")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToUpper(ehloResponse), "STARTTLS") {
+		return errors.New("SMTP server does not advertise STARTTLS")
+	}
+
+	if _, err := fmt.Fprintf(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	_, err = readSMTPResponse(reader, "220")
+	return err
+}
+
+// readSMTPResponse reads a possibly multi-line SMTP response and checks it has the expected code
+func readSMTPResponse(reader *bufio.Reader, expectedCode string) (string, error) {
+	var response string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return response, err
+		}
+		response = response + line
+		if len(line) < 4 || !strings.HasPrefix(line, expectedCode) {
+			return response, errors.New("unexpected SMTP response: " + strings.TrimSpace(line))
+		}
+		// A space after the code marks the last line of the response
+		if line[3] == ' ' {
+			return response, nil
+		}
+	}
+}
+
+// negotiateLDAPStartTLS sends the LDAPv3 StartTLS extended request and checks for a successful result
+func negotiateLDAPStartTLS(conn net.Conn) error {
+	// ExtendedRequest ::= [APPLICATION 23] SEQUENCE { requestName [0] LDAPOID }
+	requestName := append([]byte{0x80, byte(len(ldapStartTLSOID))}, []byte(ldapStartTLSOID)...)
+	extendedRequest := append([]byte{0x77, byte(len(requestName))}, requestName...)
+	// LDAPMessage ::= SEQUENCE { messageID INTEGER, protocolOp }
+	messageBody := append([]byte{0x02, 0x01, 0x01}, extendedRequest...)
+	message := append([]byte{0x30, byte(len(messageBody))}, messageBody...)
+
+	if _, err := conn.Write(message); err != nil {
+		return err
+	}
+
+	// Read the LDAPMessage envelope
+	tag, body, err := readBERElement(conn)
+	if err != nil {
+		return err
+	}
+	if tag != 0x30 {
+		return errors.New("unexpected LDAP response")
+	}
+
+	// Skip over the messageID
+	_, messageIDLength, err := parseBERHeader(body)
+	if err != nil {
+		return err
+	}
+	offset := messageIDLength
+
+	// The protocolOp should be an ExtendedResponse, [APPLICATION 24]
+	if offset >= len(body) || body[offset] != 0x78 {
+		return errors.New("unexpected LDAP response operation")
+	}
+	opLength, opHeaderLength, err := parseBERHeader(body[offset:])
+	if err != nil {
+		return err
+	}
+	extendedResponse := body[offset+opHeaderLength-opLength : offset+opHeaderLength]
+
+	// The first element of the ExtendedResponse is the resultCode ENUMERATED
+	if len(extendedResponse) < 3 || extendedResponse[0] != 0x0a || extendedResponse[1] != 0x01 {
+		return errors.New("unexpected LDAP result code")
+	}
+	if extendedResponse[2] != 0x00 {
+		return fmt.Errorf("LDAP StartTLS refused with result code %d", extendedResponse[2])
+	}
+	return nil
+}
+
+// readBERElement reads a single BER encoded element from the connection and returns its tag and contents
+func readBERElement(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(header[1])
+	// Long form lengths hold the number of length bytes in the lower 7 bits
+	if header[1]&0x80 != 0 {
+		lengthBytes := make([]byte, int(header[1]&0x7f))
+		if len(lengthBytes) > 4 {
+			return 0, nil, errors.New("BER element length too long")
+		}
+		if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range lengthBytes {
+			length = (length << 8) | int(b)
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+// parseBERHeader returns the content length of the BER element at the start of the byte slice and the total size of the element
+func parseBERHeader(data []byte) (int, int, error) {
+	if len(data) < 2 {
+		return 0, 0, errors.New("BER element too short")
+	}
+
+	length := int(data[1])
+	headerLength := 2
+	if data[1]&0x80 != 0 {
+		lengthByteCount := int(data[1] & 0x7f)
+		if lengthByteCount > 4 || len(data) < 2+lengthByteCount {
+			return 0, 0, errors.New("invalid BER element length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+lengthByteCount] {
+			length = (length << 8) | int(b)
+		}
+		headerLength = headerLength + lengthByteCount
+	}
+
+	if len(data) < headerLength+length {
+		return 0, 0, errors.New("BER element truncated")
+	}
+	return length, headerLength + length, nil
+}
+
+// negotiatePostgresStartTLS sends the Postgres SSLRequest startup packet and checks the server will accept TLS
+func negotiatePostgresStartTLS(conn net.Conn) error {
+	sslRequest := make([]byte, 8)
+	binary.BigEndian.PutUint32(sslRequest[0:4], 8)
+	binary.BigEndian.PutUint32(sslRequest[4:8], postgresSSLRequestCode)
+
+	if _, err := conn.Write(sslRequest); err != nil {
+		return err
+	}
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return err
+	}
+	if response[0] != 'S' {
+		return errors.New("Postgres server refused the SSLRequest")
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newTestTLSCertificate creates a self-signed certificate for the local TLS listeners
+func newTestTLSCertificate(commonName string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// startTestListener accepts a single connection, runs the plaintext negotiation, and then serves TLS with the certificate picked by SNI
+func startTestListener(negotiate func(conn net.Conn) bool, certificates map[string]tls.Certificate) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	tlsConfig := &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert, ok := certificates[hello.ServerName]; ok {
+				return &cert, nil
+			}
+			cert := certificates[""]
+			return &cert, nil
+		},
+	}
+
+	go func() {
+		defer GinkgoRecover()
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if negotiate != nil && !negotiate(conn) {
+			return
+		}
+		tlsConn := tls.Server(conn, tlsConfig)
+		_ = tlsConn.Handshake()
+	}()
+
+	return listener.Addr().String()
+}
+
+var _ = Describe("ProbeTLSEndpoint", func() {
+	defaultCert := newTestTLSCertificate("default.example.com")
+	sniCert := newTestTLSCertificate("sni.example.com")
+	certificates := map[string]tls.Certificate{"": defaultCert, "sni.example.com": sniCert}
+
+	It("captures the certificate served by a plain TLS listener", func() {
+		address := startTestListener(nil, certificates)
+
+		certs, err := ProbeTLSEndpoint(address, "", "", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs).To(HaveLen(1))
+		Expect(certs[0].Subject.CommonName).To(Equal("default.example.com"))
+	})
+
+	It("sends the SNI server name override", func() {
+		address := startTestListener(nil, certificates)
+
+		certs, err := ProbeTLSEndpoint(address, "sni.example.com", "", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs[0].Subject.CommonName).To(Equal("sni.example.com"))
+	})
+
+	It("negotiates SMTP STARTTLS", func() {
+		address := startTestListener(func(conn net.Conn) bool {
+			reader := bufio.NewReader(conn)
+			_, _ = io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+			line, _ := reader.ReadString('\n')
+			if !strings.HasPrefix(line, "EHLO") {
+				return false
+			}
+			_, _ = io.WriteString(conn, "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+			line, _ = reader.ReadString('\n')
+			if strings.TrimSpace(line) != "STARTTLS" {
+				return false
+			}
+			_, _ = io.WriteString(conn, "220 Ready to start TLS\r\n")
+			return true
+		}, certificates)
+
+		certs, err := ProbeTLSEndpoint(address, "", "smtp", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs[0].Subject.CommonName).To(Equal("default.example.com"))
+	})
+
+	It("fails when the SMTP server does not advertise STARTTLS", func() {
+		address := startTestListener(func(conn net.Conn) bool {
+			reader := bufio.NewReader(conn)
+			_, _ = io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+			_, _ = reader.ReadString('\n')
+			_, _ = io.WriteString(conn, "250 mail.example.com\r\n")
+			return false
+		}, certificates)
+
+		_, err := ProbeTLSEndpoint(address, "", "smtp", 5*time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("negotiates LDAP StartTLS", func() {
+		address := startTestListener(func(conn net.Conn) bool {
+			tag, body, err := readBERElement(conn)
+			if err != nil || tag != 0x30 || !strings.Contains(string(body), ldapStartTLSOID) {
+				return false
+			}
+			// ExtendedResponse with messageID 1 and a success resultCode
+			_, _ = conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+			return true
+		}, certificates)
+
+		certs, err := ProbeTLSEndpoint(address, "", "ldap", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs[0].Subject.CommonName).To(Equal("default.example.com"))
+	})
+
+	It("negotiates the Postgres SSLRequest", func() {
+		address := startTestListener(func(conn net.Conn) bool {
+			request := make([]byte, 8)
+			if _, err := io.ReadFull(conn, request); err != nil {
+				return false
+			}
+			_, _ = conn.Write([]byte{'S'})
+			return true
+		}, certificates)
+
+		certs, err := ProbeTLSEndpoint(address, "", "postgres", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs[0].Subject.CommonName).To(Equal("default.example.com"))
+	})
+
+	It("fails when the Postgres server refuses TLS", func() {
+		address := startTestListener(func(conn net.Conn) bool {
+			request := make([]byte, 8)
+			_, _ = io.ReadFull(conn, request)
+			_, _ = conn.Write([]byte{'N'})
+			return false
+		}, certificates)
+
+		_, err := ProbeTLSEndpoint(address, "", "postgres", 5*time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+})
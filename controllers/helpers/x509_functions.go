@@ -6,30 +6,49 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/kenmoini/certificate-sentinel-operator/apis/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 /*=====================================================================================
 | x509 Certificate Helper Functions
 =====================================================================================*/
 
-// DecodeCertificateBytes decodes the byte slice from a Secret data item into a PEM and then into an x509 DER object
+// DecodeCertificateBytes decodes the byte slice from a Secret data item into PEM blocks and then into x509 DER objects - every CERTIFICATE block in a bundle is decoded
 func DecodeCertificateBytes(s []byte, lggr logr.Logger) ([]*x509.Certificate, error) {
-	block, rest := pem.Decode(s)
+	var certs []*x509.Certificate
+	rest := s
+
+	// Loop through every PEM block, bundles such as a caBundle will hold more than one Certificate
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		// Skip over anything that is not a Certificate, such as a Private Key
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
 
-	// Check to see if this can be decoded into a PEM block
-	if block == nil || block.Type != "CERTIFICATE" {
-		lggr.Info("Failed to decode PEM block containing a Certificate: " + string(rest))
+		// Parse the Certificate
+		parsedCerts, err := x509.ParseCertificates(block.Bytes)
+		if err != nil {
+			lggr.Error(err, "Failed to decode certificate!")
+			continue
+		}
+		certs = append(certs, parsedCerts...)
 	}
 
-	// Parse the Certificate
-	certs, err := x509.ParseCertificates(block.Bytes)
-	if err != nil {
-		lggr.Error(err, "Failed to decode certificate!")
-		return nil, err
+	// Check to see if anything could be decoded into a Certificate
+	if len(certs) == 0 {
+		lggr.Info("Failed to decode PEM block containing a Certificate")
+		return nil, errors.New("no PEM encoded certificates found")
 	}
 	return certs, nil
 }
@@ -52,6 +71,14 @@ func ParseCertificateIntoObjects(cert *x509.Certificate, timeOut []configv1.Time
 	}
 	// Create CertificateInformation object
 	certInfo := configv1.CertificateInformation{Namespace: namespace, Name: name, DataKey: dataKey, Kind: kind, APIVersion: apiVersion, Expiration: expirationDate.String(), CommonName: cert.Subject.CommonName, CertificateAuthorityCommonName: cert.Issuer.CommonName, IsCertificateAuthority: cert.IsCA, TriggeredDaysOut: triggeredDaysOut}
+	certInfo.NotBefore, certInfo.NotAfter = metav1.NewTime(cert.NotBefore).Rfc3339Copy(), metav1.NewTime(expirationDate).Rfc3339Copy()
+	certInfo.Expired, certInfo.NotYetValid, certInfo.DaysRemaining = CertificateValidity(cert, time.Now())
+	if certInfo.Expired {
+		messagesL = append(messagesL, "Certificate has expired! Date: "+expirationDate.String())
+	}
+	if certInfo.NotYetValid {
+		messagesL = append(messagesL, "Certificate is not valid yet! Date: "+cert.NotBefore.String())
+	}
 
 	// This certificate is not expired
 	discoveredL = append(discoveredL, certInfo)
@@ -78,6 +105,14 @@ func ParseKeystoreCertificateIntoObjects(cert *x509.Certificate, timeOut []confi
 	}
 	// Create KeystoreInformation object
 	certInfo := configv1.KeystoreInformation{Namespace: namespace, Name: name, DataKey: dataKey, Kind: kind, APIVersion: apiVersion, KeystoreAlias: keystoreAlias, Expiration: expirationDate.String(), CommonName: cert.Subject.CommonName, CertificateAuthorityCommonName: cert.Issuer.CommonName, IsCertificateAuthority: cert.IsCA, TriggeredDaysOut: triggeredDaysOut}
+	certInfo.NotBefore, certInfo.NotAfter = metav1.NewTime(cert.NotBefore).Rfc3339Copy(), metav1.NewTime(expirationDate).Rfc3339Copy()
+	certInfo.Expired, certInfo.NotYetValid, certInfo.DaysRemaining = CertificateValidity(cert, time.Now())
+	if certInfo.Expired {
+		messagesL = append(messagesL, "Certificate has expired! Date: "+expirationDate.String())
+	}
+	if certInfo.NotYetValid {
+		messagesL = append(messagesL, "Certificate is not valid yet! Date: "+cert.NotBefore.String())
+	}
 
 	// This certificate is not expired
 	discoveredL = append(discoveredL, certInfo)
@@ -86,6 +121,19 @@ func ParseKeystoreCertificateIntoObjects(cert *x509.Certificate, timeOut []confi
 	return discoveredL, messagesL
 }
 
+// CertificateValidity returns if a certificate has expired or is not valid yet at the given time, and the number of whole days until it expires
+func CertificateValidity(cert *x509.Certificate, now time.Time) (expired bool, notYetValid bool, daysRemaining int) {
+	expired = now.After(cert.NotAfter)
+	notYetValid = now.Before(cert.NotBefore)
+	remaining := cert.NotAfter.Sub(now)
+	daysRemaining = int(remaining / (24 * time.Hour))
+	// Round towards the past so a certificate that expired hours ago has -1 days remaining
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		daysRemaining--
+	}
+	return expired, notYetValid, daysRemaining
+}
+
 // ParseCertificatesIntoLists [DEPRECIATED] takes a slice of certificates and all the other supporting information to create a CertificateInformation return to add to .status/alert report
 func ParseCertificatesIntoLists(certs []*x509.Certificate, timeOut []configv1.TimeSlice, namespace string, name string, dataKey string, kind string, apiVersion string) (discovered []configv1.CertificateInformation, expired []configv1.CertificateInformation, messages []string) {
 	discoveredL := []configv1.CertificateInformation{}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Polyglot Systems.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificateValidity", func() {
+	now := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	It("counts the whole days remaining on a valid certificate", func() {
+		cert := &x509.Certificate{NotBefore: now.AddDate(0, -1, 0), NotAfter: now.Add(10*24*time.Hour + time.Hour)}
+		expired, notYetValid, daysRemaining := CertificateValidity(cert, now)
+		Expect(expired).To(BeFalse())
+		Expect(notYetValid).To(BeFalse())
+		Expect(daysRemaining).To(Equal(10))
+	})
+
+	It("flags an expired certificate with negative days remaining", func() {
+		cert := &x509.Certificate{NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.Add(-time.Hour)}
+		expired, notYetValid, daysRemaining := CertificateValidity(cert, now)
+		Expect(expired).To(BeTrue())
+		Expect(notYetValid).To(BeFalse())
+		Expect(daysRemaining).To(Equal(-1))
+	})
+
+	It("flags a certificate that is not valid yet", func() {
+		cert := &x509.Certificate{NotBefore: now.AddDate(0, 0, 2), NotAfter: now.AddDate(1, 0, 0)}
+		expired, notYetValid, _ := CertificateValidity(cert, now)
+		Expect(expired).To(BeFalse())
+		Expect(notYetValid).To(BeTrue())
+	})
+})
+
+var _ = Describe("DecodeCertificateBytes", func() {
+	// Each block is either the common name of a certificate to create, a non-certificate PEM block, a CERTIFICATE block that does not parse, or text outside of any PEM block
+	table.DescribeTable("decodes every certificate PEM block",
+		func(blocks []string, expectedCommonNames []string) {
+			var data []byte
+			for _, block := range blocks {
+				switch block {
+				case "private-key":
+					data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("not a certificate")})...)
+				case "garbage-certificate":
+					data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not DER")})...)
+				case "text":
+					data = append(data, []byte("# bundle generated by hand\n")...)
+				default:
+					data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newTestTLSCertificate(block).Certificate[0]})...)
+				}
+			}
+
+			certs, err := DecodeCertificateBytes(data, logr.Discard())
+			if len(expectedCommonNames) == 0 {
+				Expect(err).To(HaveOccurred())
+				Expect(certs).To(BeEmpty())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			var commonNames []string
+			for _, cert := range certs {
+				commonNames = append(commonNames, cert.Subject.CommonName)
+			}
+			Expect(commonNames).To(Equal(expectedCommonNames))
+		},
+		table.Entry("a single certificate", []string{"leaf.example.com"}, []string{"leaf.example.com"}),
+		table.Entry("a multi-certificate bundle", []string{"leaf.example.com", "intermediate-ca", "root-ca"}, []string{"leaf.example.com", "intermediate-ca", "root-ca"}),
+		table.Entry("a key pair with the key first", []string{"private-key", "leaf.example.com"}, []string{"leaf.example.com"}),
+		table.Entry("text around the blocks", []string{"text", "leaf.example.com", "text", "root-ca"}, []string{"leaf.example.com", "root-ca"}),
+		table.Entry("a certificate block that does not parse", []string{"garbage-certificate", "root-ca"}, []string{"root-ca"}),
+		table.Entry("only a private key", []string{"private-key"}, nil),
+		table.Entry("only garbage", []string{"garbage-certificate", "text"}, nil),
+		table.Entry("nothing", []string{}, nil),
+	)
+
+	It("returns an error for data that is not PEM", func() {
+		_, err := DecodeCertificateBytes([]byte(strings.Repeat("garbage", 10)), logr.Discard())
+		Expect(err).To(MatchError("no PEM encoded certificates found"))
+	})
+})
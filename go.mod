@@ -4,10 +4,17 @@ go 1.16
 
 require (
 	github.com/go-logr/logr v0.4.0
+	github.com/go-test/deep v1.1.1 // indirect
+	github.com/google/cel-go v0.10.1
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.10.2
 	github.com/pavel-v-chernykh/keystore-go/v4 v4.1.0
-	github.com/xhit/go-simple-mail/v2 v2.10.0
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/toorop/go-dkim v0.0.0-20201103131630-e1cd1a0a5208
+	github.com/xhit/go-simple-mail/v2 v2.16.0
+	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	google.golang.org/protobuf v1.27.1
 	k8s.io/api v0.20.2
 	k8s.io/apimachinery v0.20.2
 	k8s.io/client-go v0.20.2